@@ -0,0 +1,43 @@
+package sookie
+
+// WithBindName configures whether values set as cookies are bound to the
+// cookie name, which is authenticated along with the header, so a value set
+// as one cookie fails to open as another, such as a session cookie being
+// substituted for a CSRF cookie. Binding applies to the functions setting and
+// getting cookies by name, while values sealed or opened directly, such as
+// using Seal and Open, are not bound to any name. Bound values fail to open
+// with the ErrDecrypt error under a different name, or using a Codec without
+// this option, so enabling it invalidates existing cookies.
+func WithBindName(bind bool) Option {
+	return func(c *Codec) error {
+		c.bindName = bind
+		return nil
+	}
+}
+
+// forName returns the Codec to seal or open the cookie with the given name,
+// which is a copy bound to the name if binding is enabled.
+func (c *Codec) forName(name string) *Codec {
+	if !c.bindName || c.name == name {
+		return c
+	}
+	nc := *c
+	nc.name = name
+	return &nc
+}
+
+// additionalData returns the data authenticated along with the ciphertext,
+// which is the header followed by the bound cookie name, if any, and by a zero
+// byte and the label of a LabeledKeyring, if any. Neither cookie names nor
+// labels contain a zero byte, so the two can not be confused.
+func (c *Codec) additionalData(header []byte) []byte {
+	if c.name == "" && c.label == "" {
+		return header
+	}
+	ad := make([]byte, 0, len(header)+len(c.name)+1+len(c.label))
+	ad = append(append(ad, header...), c.name...)
+	if c.label != "" {
+		ad = append(append(ad, 0), c.label...)
+	}
+	return ad
+}
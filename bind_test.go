@@ -0,0 +1,47 @@
+package sookie_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/daaku/ensure"
+	"github.com/daaku/sookie"
+)
+
+func TestBindName(t *testing.T) {
+	c, err := sookie.New(secret, sookie.WithBindName(true))
+	ensure.Nil(t, err)
+	w := httptest.NewRecorder()
+	ensure.Nil(t, c.Set(w, given, http.Cookie{Name: "session"}))
+	value := w.Result().Cookies()[0].Value
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(&http.Cookie{Name: "session", Value: value})
+	r.AddCookie(&http.Cookie{Name: "csrf", Value: value})
+	var actual Flash
+	ensure.Nil(t, c.Get(r, "session", &actual))
+	ensure.DeepEqual(t, actual, given)
+	ensure.True(t, errors.Is(c.Get(r, "csrf", &actual), sookie.ErrDecrypt))
+	ensure.True(t, errors.Is(c.Open(value, &actual), sookie.ErrDecrypt))
+
+	unbound, err := sookie.New(secret)
+	ensure.Nil(t, err)
+	ensure.True(t, errors.Is(unbound.Get(r, "session", &actual), sookie.ErrDecrypt))
+}
+
+func TestBindNameKeyring(t *testing.T) {
+	k := sookie.NewKeyring(&staticProvider{current: secret}, sookie.WithBindName(true))
+	w := httptest.NewRecorder()
+	ensure.Nil(t, k.Set(w, given, http.Cookie{Name: "session"}))
+	value := w.Result().Cookies()[0].Value
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(&http.Cookie{Name: "session", Value: value})
+	r.AddCookie(&http.Cookie{Name: "csrf", Value: value})
+	var actual Flash
+	ensure.Nil(t, k.Get(r, "session", &actual))
+	ensure.DeepEqual(t, actual, given)
+	ensure.True(t, errors.Is(k.Get(r, "csrf", &actual), sookie.ErrDecrypt))
+}
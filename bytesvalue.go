@@ -0,0 +1,85 @@
+package sookie
+
+import (
+	"encoding/binary"
+	"errors"
+	"time"
+)
+
+// bytesValueHeaderSize is the size of the expiry and creation time before the
+// payload of a bytes value.
+const bytesValueHeaderSize = 16
+
+// bytesMarshaler identifies bytes values in the format header. It is never
+// used to marshal, as bytes values are not wrapped.
+type bytesMarshaler struct{}
+
+var errBytesMarshaler = errors.New("sookie: bytes values are not marshaled")
+
+func (bytesMarshaler) ID() byte                        { return bytesID }
+func (bytesMarshaler) Marshal(any) ([]byte, error)     { return nil, errBytesMarshaler }
+func (bytesMarshaler) Unmarshal(_ []byte, _ any) error { return errBytesMarshaler }
+
+// SealBytesValue is like Seal, but for a payload which is already serialized,
+// such as a marshaled protocol buffer. The payload is stored as is, along with
+// the expiry, instead of being marshaled, and is still compressed and
+// encrypted. It can only be opened using OpenBytesValue.
+func SealBytesValue(secret []byte, expires time.Time, payload []byte) (string, error) {
+	c, err := New(secret)
+	if err != nil {
+		return "", err
+	}
+	return c.SealBytesValue(expires, payload)
+}
+
+// OpenBytesValue opens a raw value sealed by SealBytesValue, returning the
+// payload and its expiry, which is zero if the value never expires. If the
+// raw value is expired, the payload is still returned along with the
+// ErrExpired error.
+func OpenBytesValue(secret []byte, raw string) ([]byte, time.Time, error) {
+	c, err := New(secret)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return c.OpenBytesValue(raw)
+}
+
+// bytesCodec returns a copy of the Codec which seals and opens bytes values.
+func (c *Codec) bytesCodec() *Codec {
+	bc := *c
+	bc.marshaler = bytesMarshaler{}
+	return &bc
+}
+
+// SealBytesValue seals a serialized payload like the package level
+// SealBytesValue function.
+func (c *Codec) SealBytesValue(expires time.Time, payload []byte) (string, error) {
+	var e int64 = -1
+	if expires = c.sealExpiry(expires); !expires.IsZero() {
+		e = expires.Unix()
+	}
+	plaintext := make([]byte, bytesValueHeaderSize, bytesValueHeaderSize+len(payload))
+	binary.BigEndian.PutUint64(plaintext, uint64(e))
+	binary.BigEndian.PutUint64(plaintext[8:], uint64(c.now().Unix()))
+	return c.bytesCodec().seal(append(plaintext, payload...), nil)
+}
+
+// OpenBytesValue opens a serialized payload like the package level
+// OpenBytesValue function.
+func (c *Codec) OpenBytesValue(raw string) ([]byte, time.Time, error) {
+	plaintext, err := c.bytesCodec().open(raw)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	if len(plaintext) < bytesValueHeaderSize {
+		return nil, time.Time{}, ErrInvalidLength
+	}
+	e := int64(binary.BigEndian.Uint64(plaintext))
+	created := int64(binary.BigEndian.Uint64(plaintext[8:]))
+	payload := plaintext[bytesValueHeaderSize:]
+	err = c.checkExpiry(meta{expiry: e, created: created})
+	if e == -1 {
+		return payload, time.Time{}, err
+	}
+	return payload, time.Unix(e, 0), err
+}
@@ -0,0 +1,50 @@
+package sookie_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/daaku/ensure"
+	"github.com/daaku/sookie"
+)
+
+func TestBytesValue(t *testing.T) {
+	payload := []byte("\x08\x96\x01 already serialized")
+	expires := time.Now().Add(time.Hour)
+	sealed, err := sookie.SealBytesValue(secret, expires, payload)
+	ensure.Nil(t, err)
+	actual, actualExpires, err := sookie.OpenBytesValue(secret, sealed)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, actual, payload)
+	ensure.DeepEqual(t, actualExpires.Unix(), expires.Unix())
+}
+
+func TestBytesValuePermanent(t *testing.T) {
+	sealed, err := sookie.SealBytesValue(secret, time.Time{}, []byte("payload"))
+	ensure.Nil(t, err)
+	actual, expires, err := sookie.OpenBytesValue(secret, sealed)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, string(actual), "payload")
+	ensure.True(t, expires.IsZero())
+}
+
+func TestBytesValueExpired(t *testing.T) {
+	sealed, err := sookie.SealBytesValue(secret, time.Now().Add(-time.Hour), []byte("payload"))
+	ensure.Nil(t, err)
+	actual, _, err := sookie.OpenBytesValue(secret, sealed)
+	ensure.True(t, errors.Is(err, sookie.ErrExpired))
+	ensure.DeepEqual(t, string(actual), "payload")
+}
+
+func TestBytesValueNotMixed(t *testing.T) {
+	sealed, err := sookie.SealBytesValue(secret, time.Time{}, []byte("payload"))
+	ensure.Nil(t, err)
+	_, err = sookie.Open[[]byte](secret, sealed)
+	ensure.True(t, errors.Is(err, sookie.ErrFormat))
+
+	value, err := sookie.Seal(secret, time.Time{}, []byte("payload"))
+	ensure.Nil(t, err)
+	_, _, err = sookie.OpenBytesValue(secret, value)
+	ensure.True(t, errors.Is(err, sookie.ErrFormat))
+}
@@ -0,0 +1,68 @@
+package sookie
+
+import (
+	"context"
+	"crypto/cipher"
+	"reflect"
+	"sync"
+)
+
+// openCacheKey is the context key for the openCache.
+type openCacheKey struct{}
+
+// openCache memoizes opened values, see CacheOpens.
+type openCache struct {
+	values sync.Map // map[openKey]opened
+}
+
+// openKey identifies an opened value by the secret and cookie name it was
+// opened with, the raw value and the destination type.
+type openKey struct {
+	aead cipher.AEAD
+	name string
+	raw  string
+	dst  reflect.Type
+}
+
+// opened is a memoized value along with its metadata.
+type opened struct {
+	value reflect.Value
+	m     meta
+}
+
+// CacheOpens returns a context which memoizes the values opened using it,
+// such as by GetCtx, so opening the same raw value into the same type again
+// copies the value instead of decrypting it again. This is useful when
+// several layers of a handler get the same cookie. The cache lives as long as
+// the context, so it should be scoped to a single request. Values are copied
+// shallowly, so maps, slices and pointers in them are shared between the
+// copies. Values opened from the cache are not checked for expiry or replay
+// again.
+func CacheOpens(ctx context.Context) context.Context {
+	return context.WithValue(ctx, openCacheKey{}, &openCache{})
+}
+
+// openCacheFrom returns the openCache in the context, if any.
+func openCacheFrom(ctx context.Context) *openCache {
+	cache, _ := ctx.Value(openCacheKey{}).(*openCache)
+	return cache
+}
+
+// load copies a memoized value into dst, reporting if there was one.
+func (oc *openCache) load(key openKey, dst any) (meta, bool) {
+	v, ok := oc.values.Load(key)
+	if !ok {
+		return meta{}, false
+	}
+	o := v.(opened)
+	reflect.ValueOf(dst).Elem().Set(o.value)
+	return o.m, true
+}
+
+// store memoizes a copy of the value opened into dst.
+func (oc *openCache) store(key openKey, dst any, m meta) {
+	elem := reflect.ValueOf(dst).Elem()
+	value := reflect.New(elem.Type()).Elem()
+	value.Set(elem)
+	oc.values.Store(key, opened{value: value, m: m})
+}
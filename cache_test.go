@@ -0,0 +1,44 @@
+package sookie_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/daaku/ensure"
+	"github.com/daaku/sookie"
+)
+
+func TestCacheOpens(t *testing.T) {
+	var stages int
+	c, err := sookie.New(secret, sookie.WithObserver(func(stage string, d time.Duration) {
+		if stage == "decrypt" {
+			stages++
+		}
+	}))
+	ensure.Nil(t, err)
+	w := httptest.NewRecorder()
+	ensure.Nil(t, c.Set(w, given, http.Cookie{Name: cookieName}))
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Cookie", w.Header().Get("Set-Cookie"))
+
+	ctx := sookie.CacheOpens(context.Background())
+	for range 3 {
+		var actual Flash
+		ensure.Nil(t, c.GetCtx(ctx, r, cookieName, &actual))
+		ensure.DeepEqual(t, actual, given)
+	}
+	ensure.DeepEqual(t, stages, 1)
+
+	// a different type is opened again
+	var m map[string]any
+	ensure.Nil(t, c.GetCtx(ctx, r, cookieName, &m))
+	ensure.DeepEqual(t, stages, 2)
+
+	// without the cache every call decrypts
+	var actual Flash
+	ensure.Nil(t, c.GetCtx(context.Background(), r, cookieName, &actual))
+	ensure.DeepEqual(t, stages, 3)
+}
@@ -0,0 +1,309 @@
+package sookie
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// CBOR returns a Marshaler for CBOR, RFC 8949, using the given functions to
+// marshal and unmarshal the value, such as the Marshal and Unmarshal functions
+// from github.com/fxamacker/cbor/v2. This keeps sookie free of a CBOR
+// dependency while recording CBOR in the format header.
+//
+// The wrapper around the value is encoded by sookie itself, so its keys do
+// not depend on the options of the CBOR library. It is a map, major type 5,
+// with text string keys, major type 3, in this order:
+//
+//   - "V", the value as encoded by marshal, always present.
+//   - "E", the expiry as Unix seconds, or -1 if it never expires, an integer
+//     of major type 0 or 1, always present.
+//   - "C", the time it was sealed as Unix seconds, an integer.
+//   - "I", the unique ID of single use values, a byte string, major type 2.
+//   - "D", the idle timeout in seconds, an integer.
+//   - "P", the expiry as Unix milliseconds, see WithPreciseExpiry, an integer.
+//   - "T", the tag of values sealed using SealTagged, a text string.
+//
+// Keys other than "V" and "E" are left out when they are zero. All lengths
+// are definite and integers use the shortest encoding. When unmarshaling,
+// keys may come in any order and unknown keys are skipped.
+func CBOR(marshal func(v any) ([]byte, error), unmarshal func(data []byte, v any) error) Marshaler {
+	return cborMarshaler{marshal: marshal, unmarshal: unmarshal}
+}
+
+// CBOR major types used by the wrapper.
+const (
+	cborUint  byte = 0
+	cborNint  byte = 1
+	cborBytes byte = 2
+	cborText  byte = 3
+	cborArray byte = 4
+	cborMap   byte = 5
+	cborTag   byte = 6
+)
+
+// cborBreak ends an item of indefinite length.
+const cborBreak byte = 0xff
+
+var errCBORShort = errors.New("sookie: truncated CBOR")
+
+type cborMarshaler struct {
+	marshal   func(v any) ([]byte, error)
+	unmarshal func(data []byte, v any) error
+}
+
+func (cborMarshaler) ID() byte { return cborID }
+
+// Marshal encodes a wrapper struct as a map, using marshal for the value.
+func (m cborMarshaler) Marshal(v any) ([]byte, error) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("sookie: CBOR can only marshal the wrapper, got %T", v)
+	}
+	var fields []int
+	for i := range rv.NumField() {
+		if name := rv.Type().Field(i).Name; name == "V" || name == "E" || !rv.Field(i).IsZero() {
+			fields = append(fields, i)
+		}
+	}
+	b := cborHead(nil, cborMap, uint64(len(fields)))
+	for _, i := range fields {
+		name, f := rv.Type().Field(i).Name, rv.Field(i)
+		b = cborHead(b, cborText, uint64(len(name)))
+		b = append(b, name...)
+		switch {
+		case name == "V":
+			value, err := m.marshal(f.Interface())
+			if err != nil {
+				return nil, err
+			}
+			b = append(b, value...)
+		case f.Kind() == reflect.Int64:
+			b = cborInt(b, f.Int())
+		case f.Kind() == reflect.String:
+			b = cborHead(b, cborText, uint64(f.Len()))
+			b = append(b, f.String()...)
+		case f.Type() == reflect.TypeFor[[]byte]():
+			b = cborHead(b, cborBytes, uint64(f.Len()))
+			b = append(b, f.Bytes()...)
+		default:
+			return nil, fmt.Errorf("sookie: CBOR can not marshal wrapper field %s of type %s", name, f.Type())
+		}
+	}
+	return b, nil
+}
+
+// Unmarshal decodes a map into a wrapper struct, using unmarshal for the
+// value. Keys without a matching field are skipped.
+func (m cborMarshaler) Unmarshal(data []byte, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("sookie: CBOR can only unmarshal the wrapper, got %T", v)
+	}
+	rv = rv.Elem()
+	n, data, err := cborDefinite(data, cborMap)
+	if err != nil {
+		return err
+	}
+	for range n {
+		var key uint64
+		if key, data, err = cborDefinite(data, cborText); err != nil {
+			return err
+		}
+		if key > uint64(len(data)) {
+			return errCBORShort
+		}
+		name := string(data[:key])
+		data = data[key:]
+		size, err := cborItemLen(data)
+		if err != nil {
+			return err
+		}
+		item := data[:size]
+		data = data[size:]
+		f := rv.FieldByName(name)
+		if !f.IsValid() {
+			continue
+		}
+		if name == "V" {
+			if err := m.unmarshal(item, f.Addr().Interface()); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := cborDecodeField(item, f); err != nil {
+			return fmt.Errorf("sookie: CBOR wrapper field %s: %w", name, err)
+		}
+	}
+	if len(data) != 0 {
+		return errors.New("sookie: trailing data after CBOR wrapper")
+	}
+	return nil
+}
+
+// cborDecodeField decodes an integer, text or byte string into the field.
+func cborDecodeField(item []byte, f reflect.Value) error {
+	switch {
+	case f.Kind() == reflect.Int64:
+		if len(item) == 0 {
+			return errCBORShort
+		}
+		major, arg, _, _, err := cborReadHead(item)
+		if err != nil {
+			return err
+		}
+		if (major != cborUint && major != cborNint) || arg > math.MaxInt64 {
+			return errors.New("expected an int64")
+		}
+		if major == cborNint {
+			f.SetInt(-1 - int64(arg))
+		} else {
+			f.SetInt(int64(arg))
+		}
+	case f.Kind() == reflect.String:
+		n, rest, err := cborDefinite(item, cborText)
+		if err != nil {
+			return err
+		}
+		f.SetString(string(rest[:n]))
+	case f.Type() == reflect.TypeFor[[]byte]():
+		n, rest, err := cborDefinite(item, cborBytes)
+		if err != nil {
+			return err
+		}
+		f.SetBytes(append([]byte(nil), rest[:n]...))
+	default:
+		return fmt.Errorf("unsupported type %s", f.Type())
+	}
+	return nil
+}
+
+// cborHead appends the head of an item of the major type with the argument,
+// using the shortest encoding.
+func cborHead(b []byte, major byte, arg uint64) []byte {
+	major <<= 5
+	switch {
+	case arg < 24:
+		return append(b, major|byte(arg))
+	case arg <= math.MaxUint8:
+		return append(b, major|24, byte(arg))
+	case arg <= math.MaxUint16:
+		return append(b, major|25, byte(arg>>8), byte(arg))
+	case arg <= math.MaxUint32:
+		return append(b, major|26, byte(arg>>24), byte(arg>>16), byte(arg>>8), byte(arg))
+	}
+	return append(b, major|27, byte(arg>>56), byte(arg>>48), byte(arg>>40), byte(arg>>32),
+		byte(arg>>24), byte(arg>>16), byte(arg>>8), byte(arg))
+}
+
+// cborInt appends an integer.
+func cborInt(b []byte, i int64) []byte {
+	if i < 0 {
+		return cborHead(b, cborNint, uint64(-1-i))
+	}
+	return cborHead(b, cborUint, uint64(i))
+}
+
+// cborReadHead reads the head of an item, returning its major type, argument
+// and size, and whether it has an indefinite length.
+func cborReadHead(data []byte) (major byte, arg uint64, size int, indefinite bool, err error) {
+	if len(data) == 0 {
+		return 0, 0, 0, false, errCBORShort
+	}
+	major, info := data[0]>>5, data[0]&0x1f
+	switch {
+	case info < 24:
+		return major, uint64(info), 1, false, nil
+	case info <= 27:
+		size = 1 << (info - 24)
+		if len(data) < 1+size {
+			return 0, 0, 0, false, errCBORShort
+		}
+		for _, b := range data[1 : 1+size] {
+			arg = arg<<8 | uint64(b)
+		}
+		return major, arg, 1 + size, false, nil
+	case info == 31 && major >= cborBytes && major <= cborMap:
+		return major, 0, 1, true, nil
+	}
+	return 0, 0, 0, false, fmt.Errorf("sookie: invalid CBOR head 0x%02x", data[0])
+}
+
+// cborDefinite reads the head of an item of the major type with a definite
+// length, returning the length and the rest of the data. For strings the
+// length is checked against the rest of the data.
+func cborDefinite(data []byte, major byte) (uint64, []byte, error) {
+	m, arg, size, indefinite, err := cborReadHead(data)
+	if err != nil {
+		return 0, nil, err
+	}
+	if m != major || indefinite {
+		return 0, nil, fmt.Errorf("sookie: expected CBOR major type %d of definite length", major)
+	}
+	data = data[size:]
+	if (major == cborBytes || major == cborText) && arg > uint64(len(data)) {
+		return 0, nil, errCBORShort
+	}
+	return arg, data, nil
+}
+
+// cborItemLen returns the size of the item at the start of the data,
+// including any nested items.
+func cborItemLen(data []byte) (int, error) {
+	off := 0
+	// pending holds the number of items left in each open item, or -1 for
+	// items of indefinite length, which end with a break
+	pending := []int{1}
+	for len(pending) != 0 {
+		top := len(pending) - 1
+		if pending[top] == 0 {
+			pending = pending[:top]
+			continue
+		}
+		if off >= len(data) {
+			return 0, errCBORShort
+		}
+		if data[off] == cborBreak {
+			if pending[top] != -1 {
+				return 0, errors.New("sookie: unexpected CBOR break")
+			}
+			off++
+			pending = pending[:top]
+			continue
+		}
+		if pending[top] > 0 {
+			pending[top]--
+		}
+		major, arg, size, indefinite, err := cborReadHead(data[off:])
+		if err != nil {
+			return 0, err
+		}
+		off += size
+		left := uint64(len(data) - off)
+		switch {
+		case indefinite:
+			pending = append(pending, -1)
+		case major == cborBytes || major == cborText:
+			if arg > left {
+				return 0, errCBORShort
+			}
+			off += int(arg)
+		case major == cborArray || major == cborMap:
+			// every item takes at least a byte
+			if major == cborMap {
+				if arg > left/2 {
+					return 0, errCBORShort
+				}
+				arg *= 2
+			}
+			if arg > left {
+				return 0, errCBORShort
+			}
+			pending = append(pending, int(arg))
+		case major == cborTag:
+			pending = append(pending, 1)
+		}
+	}
+	return off, nil
+}
@@ -0,0 +1,87 @@
+package sookie_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/daaku/ensure"
+	"github.com/daaku/sookie"
+)
+
+// cborString stands in for a CBOR library, encoding strings shorter than 24
+// bytes, so the expected bytes of the wrapper are known.
+var cborString = sookie.CBOR(
+	func(v any) ([]byte, error) {
+		s, ok := v.(string)
+		if !ok || len(s) >= 24 {
+			return nil, fmt.Errorf("unsupported value %v", v)
+		}
+		return append([]byte{0x60 | byte(len(s))}, s...), nil
+	},
+	func(data []byte, v any) error {
+		p, ok := v.(*string)
+		if !ok || len(data) == 0 || data[0]&0xe0 != 0x60 || int(data[0]&0x1f) != len(data)-1 {
+			return fmt.Errorf("unsupported value % x", data)
+		}
+		*p = string(data[1:])
+		return nil
+	},
+)
+
+type cborWrapper struct {
+	V       string
+	E, C, D int64
+	I       []byte
+	T       string
+}
+
+func TestCBORMarshal(t *testing.T) {
+	ensure.DeepEqual(t, cborString.ID(), byte(2))
+	b, err := cborString.Marshal(cborWrapper{V: "hi", E: -1, C: 1700000000})
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, b, []byte{
+		0xa3,      // map of 3 pairs
+		0x61, 'V', // "V"
+		0x62, 'h', 'i', // "hi"
+		0x61, 'E', // "E"
+		0x20,      // -1
+		0x61, 'C', // "C"
+		0x1a, 0x65, 0x53, 0xf1, 0x00, // 1700000000
+	})
+}
+
+func TestCBORUnmarshal(t *testing.T) {
+	b := []byte{
+		0xa5,                        // map of 5 pairs
+		0x61, 'E', 0x39, 0x01, 0xf3, // "E": -500
+		0x61, 'X', 0x9f, 0x01, 0xa1, 0x61, 'a', 0x02, 0xff, // "X": [_ 1, {"a": 2}]
+		0x61, 'V', 0x62, 'h', 'i', // "V": "hi"
+		0x61, 'I', 0x42, 0x01, 0x02, // "I": h'0102'
+		0x61, 'T', 0x61, 't', // "T": "t"
+	}
+	var w cborWrapper
+	ensure.Nil(t, cborString.Unmarshal(b, &w))
+	ensure.DeepEqual(t, w, cborWrapper{V: "hi", E: -500, I: []byte{1, 2}, T: "t"})
+
+	ensure.NotNil(t, cborString.Unmarshal(b[:len(b)-1], &w))
+	ensure.NotNil(t, cborString.Unmarshal(append(b, 0x00), &w))
+	ensure.NotNil(t, cborString.Unmarshal([]byte{0xa1, 0x61, 'E', 0x61, 'x'}, &w))
+}
+
+func TestCBORMarshaler(t *testing.T) {
+	c, err := sookie.New(secret, sookie.WithMarshaler(cborString))
+	ensure.Nil(t, err)
+	raw, err := c.Seal(time.Time{}, "hi")
+	ensure.Nil(t, err)
+	var actual string
+	ensure.Nil(t, c.Open(raw, &actual))
+	ensure.DeepEqual(t, actual, "hi")
+	wrapper, _, err := c.OpenRaw(raw)
+	ensure.Nil(t, err)
+	ensure.True(t, bytes.HasPrefix(wrapper, []byte{0xa3, 0x61, 'V', 0x62, 'h', 'i', 0x61, 'E', 0x20, 0x61, 'C'}))
+	_, err = sookie.Open[string](secret, raw)
+	ensure.True(t, errors.Is(err, sookie.ErrFormat))
+}
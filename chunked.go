@@ -0,0 +1,94 @@
+package sookie
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// MaxChunks is the maximum number of chunks GetChunked reassembles.
+const MaxChunks = 32
+
+// GetChunked retrieves a value which was too large for a single cookie, and
+// was instead split across cookies named baseName.0 to baseName.N, each
+// holding the next part of the sealed value. The chunks are ordered by their
+// index, concatenated and opened like Get. Cookies named baseName followed by
+// a dot and a suffix which is not a number are ignored. Missing, duplicate or
+// invalid chunks fail with the ErrChunk error, and http.ErrNoCookie is
+// returned if there are no chunks.
+func GetChunked[V any](secret []byte, r *http.Request, baseName string) (V, error) {
+	var v V
+	c, err := New(secret)
+	if err != nil {
+		return v, err
+	}
+	err = c.GetChunked(r, baseName, &v)
+	return v, err
+}
+
+// GetChunked retrieves a chunked cookie like the package level GetChunked
+// function.
+func (c *Codec) GetChunked(r *http.Request, baseName string, dst any) error {
+	raw, err := chunks(r.Cookies(), baseName)
+	if err != nil {
+		return c.report(baseName, err)
+	}
+	return c.report(baseName, c.openCookie(context.Background(), baseName, raw, dst))
+}
+
+// chunks reassembles the value of the cookies named baseName.0 to baseName.N.
+func chunks(cookies []*http.Cookie, baseName string) (string, error) {
+	prefix := baseName + "."
+	var parts []string
+	found := 0
+	for _, cookie := range cookies {
+		suffix, ok := strings.CutPrefix(cookie.Name, prefix)
+		if !ok || !isDigits(suffix) {
+			continue
+		}
+		i, err := strconv.Atoi(suffix)
+		if err != nil || strconv.Itoa(i) != suffix {
+			return "", fmt.Errorf("%w: invalid index in %q", ErrChunk, cookie.Name)
+		}
+		if i >= MaxChunks {
+			return "", fmt.Errorf("%w: index %d exceeds the maximum of %d chunks", ErrChunk, i, MaxChunks)
+		}
+		if i >= len(parts) {
+			parts = append(parts, make([]string, i+1-len(parts))...)
+		}
+		if parts[i] != "" {
+			return "", fmt.Errorf("%w: duplicate chunk %d", ErrChunk, i)
+		}
+		if cookie.Value == "" {
+			return "", fmt.Errorf("%w: empty chunk %d", ErrChunk, i)
+		}
+		parts[i] = cookie.Value
+		found++
+	}
+	if found == 0 {
+		return "", http.ErrNoCookie
+	}
+	for i, part := range parts {
+		if part == "" {
+			return "", fmt.Errorf("%w: missing chunk %d of %d", ErrChunk, i, len(parts))
+		}
+	}
+	return strings.Join(parts, ""), nil
+}
+
+// isDigits reports if s is non-empty and only contains ASCII digits. Cookies
+// sharing the base name but with other suffixes, such as baseName.sig, are
+// not chunks.
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,77 @@
+package sookie_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daaku/ensure"
+	"github.com/daaku/sookie"
+)
+
+// chunkedRequest returns a request with the chunks of raw as cookies, in the
+// order of the given indexes.
+func chunkedRequest(raw string, indexes ...int) *http.Request {
+	size := (len(raw) + 2) / 3
+	var parts []string
+	for len(raw) > size {
+		parts = append(parts, raw[:size])
+		raw = raw[size:]
+	}
+	parts = append(parts, raw)
+	r := httptest.NewRequest("GET", "/", nil)
+	for _, i := range indexes {
+		r.AddCookie(&http.Cookie{Name: fmt.Sprintf("%s.%d", cookieName, i), Value: parts[i]})
+	}
+	return r
+}
+
+func TestGetChunked(t *testing.T) {
+	value := Flash{Kind: "info", Content: strings.Repeat("x", 100)}
+	raw, err := sookie.Seal(secret, time.Time{}, value)
+	ensure.Nil(t, err)
+	actual, err := sookie.GetChunked[Flash](secret, chunkedRequest(raw, 2, 0, 1), cookieName)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, actual, value)
+}
+
+func TestGetChunkedIgnoresOtherSuffixes(t *testing.T) {
+	value := Flash{Kind: "info", Content: strings.Repeat("x", 100)}
+	raw, err := sookie.Seal(secret, time.Time{}, value)
+	ensure.Nil(t, err)
+	r := chunkedRequest(raw, 2, 0, 1)
+	r.AddCookie(&http.Cookie{Name: cookieName + ".sig", Value: "x"})
+	r.AddCookie(&http.Cookie{Name: cookieName + ".", Value: "x"})
+	r.AddCookie(&http.Cookie{Name: cookieName + ".-1", Value: "x"})
+	actual, err := sookie.GetChunked[Flash](secret, r, cookieName)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, actual, value)
+
+	r = chunkedRequest(raw)
+	r.AddCookie(&http.Cookie{Name: cookieName + ".sig", Value: "x"})
+	_, err = sookie.GetChunked[Flash](secret, r, cookieName)
+	ensure.True(t, errors.Is(err, http.ErrNoCookie))
+}
+
+func TestGetChunkedInvalid(t *testing.T) {
+	raw, err := sookie.Seal(secret, time.Time{}, given)
+	ensure.Nil(t, err)
+
+	_, err = sookie.GetChunked[Flash](secret, chunkedRequest(raw, 0, 2), cookieName)
+	ensure.True(t, errors.Is(err, sookie.ErrChunk))
+
+	_, err = sookie.GetChunked[Flash](secret, chunkedRequest(raw, 0, 1, 1, 2), cookieName)
+	ensure.True(t, errors.Is(err, sookie.ErrChunk))
+
+	r := chunkedRequest(raw, 0, 1, 2)
+	r.AddCookie(&http.Cookie{Name: cookieName + ".01", Value: "x"})
+	_, err = sookie.GetChunked[Flash](secret, r, cookieName)
+	ensure.True(t, errors.Is(err, sookie.ErrChunk))
+
+	_, err = sookie.GetChunked[Flash](secret, chunkedRequest(raw), cookieName)
+	ensure.True(t, errors.Is(err, http.ErrNoCookie))
+}
@@ -0,0 +1,99 @@
+package sookie
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// MaxCleartextSize is the largest cleartext which can be sealed along with a
+// value, see SealCleartext.
+const MaxCleartextSize = 255
+
+// SealCleartext is like Seal, but also stores a few bytes of cleartext in the
+// header, such as the shard of the user, which an edge component can read
+// using Cleartext without the secret. The cleartext is not encrypted, but is
+// authenticated along with the header, so altering it makes opening fail with
+// the ErrDecrypt error. It may be at most MaxCleartextSize bytes.
+func SealCleartext[V any](secret []byte, expires time.Time, cleartext []byte, value V) (string, error) {
+	c, err := New(secret)
+	if err != nil {
+		return "", err
+	}
+	return c.SealCleartext(expires, cleartext, value)
+}
+
+// OpenCleartext is like Open, but also returns the cleartext stored by
+// SealCleartext, which is nil for values sealed without one.
+func OpenCleartext[V any](secret []byte, raw string) (V, []byte, error) {
+	var value V
+	c, err := New(secret)
+	if err != nil {
+		return value, nil, err
+	}
+	cleartext, err := c.OpenCleartext(raw, &value)
+	return value, cleartext, err
+}
+
+// Cleartext returns the cleartext stored by SealCleartext in a raw value
+// sealed using the default encoding and no value prefix, without the secret.
+// The cleartext has not been authenticated, so it should only be used where a
+// forged value does no harm, such as routing, and the value must still be
+// opened before trusting it. Values sealed without cleartext return nil.
+func Cleartext(raw string) ([]byte, error) {
+	return cleartextOf(base64.RawURLEncoding, raw)
+}
+
+// SealCleartext seals a value along with cleartext like the package level
+// SealCleartext function.
+func (c *Codec) SealCleartext(expires time.Time, cleartext []byte, value any) (string, error) {
+	if len(cleartext) > MaxCleartextSize {
+		return "", fmt.Errorf("sookie: cleartext must be at most %d bytes, got %d",
+			MaxCleartextSize, len(cleartext))
+	}
+	if c.debug || c.signOnly {
+		return "", errors.New("sookie: cleartext requires encrypted values")
+	}
+	cc := *c
+	if len(cleartext) != 0 {
+		cc.cleartext = cleartext
+	}
+	return cc.Seal(expires, value)
+}
+
+// OpenCleartext opens a value into the value pointed to by dst like Open,
+// returning the cleartext stored with it. The cleartext is only returned if
+// the value opens, at which point it has been authenticated.
+func (c *Codec) OpenCleartext(raw string, dst any) ([]byte, error) {
+	if err := c.Open(raw, dst); err != nil {
+		return nil, err
+	}
+	return c.Cleartext(raw)
+}
+
+// Cleartext returns the cleartext of a raw value like the package level
+// Cleartext function, using the encoding and value prefix of the Codec.
+func (c *Codec) Cleartext(raw string) ([]byte, error) {
+	raw, err := c.cutPrefix(raw)
+	if err != nil {
+		return nil, err
+	}
+	return cleartextOf(c.encoding, raw)
+}
+
+// cleartextOf decodes the raw value and returns the cleartext in its header.
+func cleartextOf(encoding *base64.Encoding, raw string) ([]byte, error) {
+	message, err := encoding.DecodeString(raw)
+	if err != nil {
+		return nil, decodeError(raw, err)
+	}
+	if len(message) < minMessageSize {
+		return nil, errTooShort
+	}
+	h, _, _, err := parseHeader(message)
+	if err != nil {
+		return nil, err
+	}
+	return h.cleartext, nil
+}
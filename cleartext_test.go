@@ -0,0 +1,58 @@
+package sookie_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/daaku/ensure"
+	"github.com/daaku/sookie"
+)
+
+func TestSealCleartext(t *testing.T) {
+	raw, err := sookie.SealCleartext(secret, time.Time{}, []byte("shard-7"), given)
+	ensure.Nil(t, err)
+	cleartext, err := sookie.Cleartext(raw)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, string(cleartext), "shard-7")
+
+	actual, cleartext, err := sookie.OpenCleartext[Flash](secret, raw)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, actual, given)
+	ensure.DeepEqual(t, string(cleartext), "shard-7")
+
+	// values with cleartext also open normally
+	actual, err = sookie.Open[Flash](secret, raw)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, actual, given)
+}
+
+func TestSealCleartextNone(t *testing.T) {
+	raw, err := sookie.Seal(secret, time.Time{}, given)
+	ensure.Nil(t, err)
+	_, cleartext, err := sookie.OpenCleartext[Flash](secret, raw)
+	ensure.Nil(t, err)
+	ensure.True(t, cleartext == nil)
+}
+
+func TestSealCleartextTampered(t *testing.T) {
+	raw, err := sookie.SealCleartext(secret, time.Time{}, []byte("shard-7"), given)
+	ensure.Nil(t, err)
+	message, err := base64.RawURLEncoding.DecodeString(raw)
+	ensure.Nil(t, err)
+	i := bytes.Index(message, []byte("shard-7"))
+	message[i+6] = '8'
+	tampered := base64.RawURLEncoding.EncodeToString(message)
+	cleartext, err := sookie.Cleartext(tampered)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, string(cleartext), "shard-8")
+	_, _, err = sookie.OpenCleartext[Flash](secret, tampered)
+	ensure.True(t, errors.Is(err, sookie.ErrDecrypt))
+}
+
+func TestSealCleartextTooLarge(t *testing.T) {
+	_, err := sookie.SealCleartext(secret, time.Time{}, make([]byte, sookie.MaxCleartextSize+1), given)
+	ensure.NotNil(t, err)
+}
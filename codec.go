@@ -0,0 +1,1675 @@
+package sookie
+
+import (
+	"context"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log/slog"
+	mrand "math/rand/v2"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// DefaultMaxDecompressedSize is the default limit for the decompressed size of
+// a value, see WithMaxDecompressedSize.
+const DefaultMaxDecompressedSize = 1 << 20
+
+var (
+	decoder, _ = newDecoder(DefaultMaxDecompressedSize, nil)
+	encoder, _ = zstd.NewWriter(nil)
+)
+
+// newDecoder creates a zstd decoder which refuses to decompress more than max
+// bytes, using the raw dictionary if it is not empty.
+func newDecoder(max int, dict []byte) (*zstd.Decoder, error) {
+	// the size is limited by WithDecoderMaxMemory alone, WithDecodeAllCapLimit
+	// would reject every frame declaring its content size, as DecodeAll is
+	// given no buffer
+	options := []zstd.DOption{zstd.WithDecoderMaxMemory(uint64(max))}
+	if len(dict) != 0 {
+		options = append(options, zstd.WithDecoderDictRaw(dictID(dict), dict))
+	}
+	return zstd.NewReader(nil, options...)
+}
+
+// dictID derives the zstd dictionary ID from its content, so frames
+// compressed using a different dictionary are rejected when decompressing.
+func dictID(dict []byte) uint32 {
+	id := crc32.ChecksumIEEE(dict)
+	if id == 0 {
+		id = 1
+	}
+	return id
+}
+
+// NonceSize is the size of the nonce a NonceSource must fill.
+const NonceSize = chacha20poly1305.NonceSizeX
+
+// NonceSource fills the NonceSize byte nonce used to seal a single value.
+// It must never repeat a nonce for the same secret.
+type NonceSource func(nonce []byte) error
+
+// Codec seals and opens values using a single secret and a set of options.
+// The package level functions use a Codec with the default options.
+// A Codec is safe for concurrent use by multiple goroutines.
+type Codec struct {
+	aead                cipher.AEAD
+	nonceSource         NonceSource
+	marshaler           Marshaler
+	maxDecompressedSize int
+	decoder             *zstd.Decoder
+	encoder             *zstd.Encoder
+	dict                []byte
+	now                 func() time.Time
+	maxJitter           time.Duration
+	tracer              Tracer
+	observer            Observer
+	blockSize           int
+	legacyDecoder       func(raw string, dst any) error
+	debug               bool
+	macKey              []byte
+	errorHook           func(name string, err error)
+	hookNoCookie        bool
+	grace               time.Duration
+	defaultExpiry       time.Duration
+	maxLifetime         time.Duration
+	strict              bool
+	replay              ReplayStore
+	encoding            *base64.Encoding
+	templates           *sync.Map // map[string]http.Cookie
+	prefix              string
+	label               string
+	rejectZero          bool
+	compressor          Compressor
+	notBefore           int64
+	idleTimeout         int64
+	preciseExpiry       bool
+	tag                 string
+	strictSecurity      bool
+	maxTotalSize        int
+	bindName            bool
+	name                string
+	versioned           bool
+	keyVersion          byte
+	signOnly            bool
+	signKey             []byte
+	rejectWeakSecret    bool
+	rawOnUnmarshalError bool
+	cleartext           []byte
+	dictSources         map[byte][]byte
+	dicts               map[byte]*zstd.Decoder
+	dictVersion         byte
+	urlDecode           bool
+}
+
+// Option configures a Codec.
+type Option func(*Codec) error
+
+// New creates a Codec using the given secret, which must be 32 bytes.
+func New(secret []byte, options ...Option) (*Codec, error) {
+	aead, err := chacha20poly1305.NewX(secret)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrAEAD, err)
+	}
+	c := &Codec{
+		aead:                aead,
+		nonceSource:         randNonce,
+		marshaler:           MsgPack,
+		maxDecompressedSize: DefaultMaxDecompressedSize,
+		decoder:             decoder,
+		encoder:             encoder,
+		now:                 time.Now,
+		encoding:            base64.RawURLEncoding,
+		templates:           new(sync.Map),
+		compressor:          Zstd,
+	}
+	for _, o := range options {
+		if err := o(c); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.checkSecret(secret); err != nil {
+		return nil, err
+	}
+	if c.strict && c.marshaler.ID() == gobID {
+		return nil, errors.New("sookie: strict unmarshal is not supported with Gob")
+	}
+	if len(c.dict) != 0 && c.compressor != Zstd {
+		return nil, errors.New("sookie: compression dictionaries are only supported with Zstd")
+	}
+	if c.urlDecode && (strings.Contains(c.prefix, "%") || strings.Contains(encodingAlphabet(c.encoding), "%")) {
+		return nil, errors.New("sookie: URL decoding can not be combined with a prefix or encoding using '%'")
+	}
+	if c.signOnly {
+		if c.debug {
+			return nil, errors.New("sookie: sign only can not be combined with debug plaintext")
+		}
+		if c.signKey, err = signKey(secret); err != nil {
+			return nil, err
+		}
+	}
+	if c.maxDecompressedSize != DefaultMaxDecompressedSize || len(c.dict) != 0 {
+		if c.decoder, err = newDecoder(c.maxDecompressedSize, c.dict); err != nil {
+			return nil, fmt.Errorf("sookie: failed to create decoder: %w", err)
+		}
+	}
+	if c.dictSources != nil {
+		if err := c.newDictDecoders(); err != nil {
+			return nil, err
+		}
+	}
+	if len(c.dict) != 0 {
+		c.encoder, err = zstd.NewWriter(nil, zstd.WithEncoderDictRaw(dictID(c.dict), c.dict))
+		if err != nil {
+			return nil, fmt.Errorf("sookie: failed to create encoder: %w", err)
+		}
+	}
+	return c, nil
+}
+
+// WithSecret returns a copy of the Codec using a different secret, which must
+// be 32 bytes, but otherwise configured with the same options. This is useful
+// for key rotation, where the Codecs for the old and new secrets should not
+// drift apart.
+func (c *Codec) WithSecret(secret []byte) (*Codec, error) {
+	aead, err := chacha20poly1305.NewX(secret)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrAEAD, err)
+	}
+	if err := c.checkSecret(secret); err != nil {
+		return nil, err
+	}
+	clone := *c
+	clone.aead = aead
+	if c.signOnly {
+		if clone.signKey, err = signKey(secret); err != nil {
+			return nil, err
+		}
+	}
+	return &clone, nil
+}
+
+// WithValuePrefix prefixes sealed values with a short human visible prefix,
+// such as "v1.", so tools like log scrubbers can recognize and redact them.
+// Opening a value fails with the ErrFormat error if it does not have the
+// prefix. The prefix may only contain characters allowed in cookie values.
+func WithValuePrefix(prefix string) Option {
+	return func(c *Codec) error {
+		if prefix == "" {
+			return errors.New("sookie: value prefix must not be empty")
+		}
+		for i := range len(prefix) {
+			if b := prefix[i]; !validCookieByte(b) {
+				return fmt.Errorf("sookie: invalid character %q in value prefix", b)
+			}
+		}
+		c.prefix = prefix
+		return nil
+	}
+}
+
+// validCookieByte reports if the byte is allowed in cookie values.
+func validCookieByte(b byte) bool {
+	return b > ' ' && b < 0x7f && b != '"' && b != ',' && b != ';' && b != '\\'
+}
+
+// WithClock configures the function used to get the current time, both when
+// computing the expiry of values set with a MaxAge, and when checking if a
+// value has expired, so a fixed clock gives predictable expiries end to end.
+// It defaults to time.Now, and is mainly useful for tests.
+func WithClock(now func() time.Time) Option {
+	return func(c *Codec) error {
+		if now == nil {
+			return errors.New("sookie: clock must not be nil")
+		}
+		c.now = now
+		return nil
+	}
+}
+
+// WithExpiryJitter adds a random duration of up to maxJitter, in whole
+// seconds, to the expiry of sealed values. This spreads out the expiry of
+// values sealed at the same time, such as sessions created in bulk, avoiding a
+// spike when they all expire at once. When setting cookies, the MaxAge or
+// Expires of the cookie is jittered by the same amount as the embedded expiry.
+// Values without an expiry are unaffected.
+func WithExpiryJitter(maxJitter time.Duration) Option {
+	return func(c *Codec) error {
+		if maxJitter < 0 {
+			return errors.New("sookie: expiry jitter must not be negative")
+		}
+		c.maxJitter = maxJitter
+		return nil
+	}
+}
+
+// WithCompressionDict configures a raw Zstandard dictionary used when
+// compressing and decompressing values. Small values which share a lot of
+// content, such as field names, compress far better with a dictionary holding
+// that content. The dictionary must be identical when sealing and opening a
+// value: values sealed with a dictionary are marked as such in the header and
+// can only be opened using the same dictionary, and a Codec with a dictionary
+// can still open values sealed without one. Changing the dictionary therefore
+// invalidates previously sealed values, much like changing the secret.
+func WithCompressionDict(dict []byte) Option {
+	return func(c *Codec) error {
+		if len(dict) == 0 {
+			return errors.New("sookie: compression dictionary must not be empty")
+		}
+		c.dict = dict
+		return nil
+	}
+}
+
+// WithLegacyDecoder configures a decoder for cookie values in an older format,
+// such as plain base64 encoded JSON, to ease migrating to sealed cookies.
+// When getting a cookie whose value is not structurally a sealed value, failing
+// with the ErrDecode, ErrInvalidLength or ErrFormat errors, the decoder is
+// called to unmarshal the raw value into dst instead. Values which fail to
+// decrypt are never passed to it. If the decoder also fails, the original error
+// is returned. Legacy values have no expiry, so setting the cookie again once
+// it has been read upgrades it to a sealed value.
+func WithLegacyDecoder(decode func(raw string, dst any) error) Option {
+	return func(c *Codec) error {
+		if decode == nil {
+			return errors.New("sookie: legacy decoder must not be nil")
+		}
+		c.legacyDecoder = decode
+		return nil
+	}
+}
+
+// WithErrorHook configures a function called whenever opening a value fails,
+// with the name of the cookie and the error, which is useful for logging and
+// metrics. It is also called when AutoRefresh fails to refresh a cookie. The
+// name is empty when opening a raw value with Open. The hook is
+// not called when the cookie is missing from the request, unless
+// WithErrorHookNoCookie is also used.
+func WithErrorHook(hook func(name string, err error)) Option {
+	return func(c *Codec) error {
+		c.errorHook = hook
+		return nil
+	}
+}
+
+// WithErrorHookNoCookie configures whether the hook set by WithErrorHook is
+// also called with the http.ErrNoCookie error when the cookie is missing.
+func WithErrorHookNoCookie(include bool) Option {
+	return func(c *Codec) error {
+		c.hookNoCookie = include
+		return nil
+	}
+}
+
+// CompatibleEncoding is a base64 encoding for clients and intermediaries
+// which mishandle some of the characters of the other encodings, such as
+// decoding '+' as a space, escaping '/' or '=', or rejecting '_'. It only uses
+// ASCII letters, digits, '-' and '.', which are unreserved in URLs and valid
+// in host names, and is not padded. See WithEncoding.
+var CompatibleEncoding = base64.NewEncoding(
+	"ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-.").
+	WithPadding(base64.NoPadding)
+
+// WithEncoding configures the base64 encoding of sealed values, for systems
+// which require a specific variant, such as base64.URLEncoding with padding,
+// CompatibleEncoding, or a custom alphabet created by base64.NewEncoding.
+// It defaults to base64.RawURLEncoding. The alphabet and padding must only
+// use characters allowed in cookie values. Values must be opened using the same encoding they
+// were sealed with, otherwise they fail to decode.
+func WithEncoding(encoding *base64.Encoding) Option {
+	return func(c *Codec) error {
+		if encoding == nil {
+			return errors.New("sookie: encoding must not be nil")
+		}
+		if b, ok := invalidEncodingByte(encoding); ok {
+			return fmt.Errorf("sookie: invalid character %q in encoding", b)
+		}
+		c.encoding = encoding
+		return nil
+	}
+}
+
+// invalidEncodingByte returns the first character the encoding may output
+// which is not allowed in cookie values, if any.
+func invalidEncodingByte(encoding *base64.Encoding) (byte, bool) {
+	encoded := encodingAlphabet(encoding)
+	for i := range len(encoded) {
+		if !validCookieByte(encoded[i]) {
+			return encoded[i], true
+		}
+	}
+	return 0, false
+}
+
+// encodingAlphabet returns a string with every character the encoding may
+// produce, including the padding, if any.
+func encodingAlphabet(encoding *base64.Encoding) string {
+	// packing the indexes 0 to 63 six bits at a time encodes to every
+	// character of the alphabet, and a trailing byte adds the padding, if any
+	src := make([]byte, 0, 49)
+	for i := 0; i < 64; i += 4 {
+		v := i<<18 | (i+1)<<12 | (i+2)<<6 | (i + 3)
+		src = append(src, byte(v>>16), byte(v>>8), byte(v))
+	}
+	return encoding.EncodeToString(append(src, 0))
+}
+
+// WithPadding pads the plaintext of sealed values, after compression, to a
+// multiple of blockSize bytes before it is encrypted. The ciphertext length
+// otherwise reveals the approximate size of the value, and padding makes
+// values of similar size indistinguishable. The padding is PKCS#7 style, so
+// blockSize must be between 1 and 255. Padding is recorded in the header and
+// removed when opening, whether or not the opening Codec uses WithPadding.
+func WithPadding(blockSize int) Option {
+	return func(c *Codec) error {
+		if blockSize < 1 || blockSize > 255 {
+			return fmt.Errorf("sookie: padding block size must be between 1 and 255, got %d", blockSize)
+		}
+		c.blockSize = blockSize
+		return nil
+	}
+}
+
+// Tracer is called when a Codec starts sealing or opening a value, with the
+// context of the operation and its name, either "seal" or "open". The returned
+// function, if not nil, is called with the resulting error once the operation
+// finishes. This allows attaching the work to a span in a tracing system.
+type Tracer func(ctx context.Context, op string) func(err error)
+
+// WithTracer configures a Tracer to be called for each seal and open.
+func WithTracer(t Tracer) Option {
+	return func(c *Codec) error {
+		c.tracer = t
+		return nil
+	}
+}
+
+// trace starts tracing the operation, returning the function to call when it
+// finishes.
+func (c *Codec) trace(ctx context.Context, op string) func(error) {
+	if c.tracer != nil {
+		if done := c.tracer(ctx, op); done != nil {
+			return done
+		}
+	}
+	return func(error) {}
+}
+
+// WithExpiryGrace accepts values for a grace period after they have expired,
+// which tolerates clock skew between the clients and servers. Values are only
+// treated as expired once the current time is after the expiry plus grace.
+// It defaults to zero, so values expire strictly at their expiry.
+func WithExpiryGrace(grace time.Duration) Option {
+	return func(c *Codec) error {
+		if grace < 0 {
+			return errors.New("sookie: expiry grace must not be negative")
+		}
+		c.grace = grace
+		return nil
+	}
+}
+
+// WithMaxLifetime bounds how long any value remains valid after it was
+// sealed, regardless of its expiry. Sealed values store their creation time,
+// and values created in the future, or claiming an expiry beyond the creation
+// time plus maxLifetime, are rejected with the ErrLifetime error, as they
+// indicate a forged value or a broken clock. Values older than maxLifetime
+// fail with the ErrExpired error, including values without an expiry. Values
+// sealed before creation times were stored are rejected. The grace period from
+// WithExpiryGrace also applies, and maxLifetime should allow for any expiry
+// jitter.
+func WithMaxLifetime(maxLifetime time.Duration) Option {
+	return func(c *Codec) error {
+		if maxLifetime < time.Second {
+			return errors.New("sookie: max lifetime must be at least a second")
+		}
+		c.maxLifetime = maxLifetime
+		return nil
+	}
+}
+
+// WithNotBefore rejects values sealed before the cutoff with the ErrStale
+// error, such as to invalidate every session issued before a security
+// incident without changing the secret. Values sealed before creation times
+// were stored are also rejected. Creation times are stored in whole seconds,
+// so values sealed during the second of the cutoff are accepted.
+func WithNotBefore(cutoff time.Time) Option {
+	return func(c *Codec) error {
+		c.notBefore = cutoff.Unix()
+		return nil
+	}
+}
+
+// WithIdleTimeout makes sealed values expire once they have not been sealed
+// again for the idle timeout, in addition to their expiry, which is what most
+// session systems want: resealing a value on each use, such as using
+// AutoRefresh, keeps it valid while it is in use. The timeout is stored in
+// the value, so it keeps applying if the option later changes, and values
+// sealed without one use the timeout of the Codec opening them. Values sealed
+// before creation times were stored are rejected by a Codec with this option.
+func WithIdleTimeout(idle time.Duration) Option {
+	return func(c *Codec) error {
+		if idle < time.Second {
+			return errors.New("sookie: idle timeout must be at least a second")
+		}
+		c.idleTimeout = int64(idle / time.Second)
+		return nil
+	}
+}
+
+// WithPreciseExpiry configures whether sealed values also store their expiry
+// in Unix milliseconds, and are opened comparing it at that precision, for
+// values which expire within a second or so. By default expiries are stored
+// in whole seconds, which is more compact. Values sealed without it are
+// still opened at second precision.
+func WithPreciseExpiry(precise bool) Option {
+	return func(c *Codec) error {
+		c.preciseExpiry = precise
+		return nil
+	}
+}
+
+// jitter returns a random duration in whole seconds of up to maxJitter.
+func (c *Codec) jitter() time.Duration {
+	if c.maxJitter < time.Second {
+		return 0
+	}
+	return mrand.N(c.maxJitter/time.Second+1) * time.Second
+}
+
+// jitterExpiry adds jitter to a non-zero expiry.
+func (c *Codec) jitterExpiry(expires time.Time) time.Time {
+	if expires.IsZero() {
+		return expires
+	}
+	return expires.Add(c.jitter())
+}
+
+// sealExpiry returns the expiry to seal a value with, applying the default
+// expiry to a zero expiry and adding jitter.
+func (c *Codec) sealExpiry(expires time.Time) time.Time {
+	if expires.IsZero() && c.defaultExpiry > 0 {
+		expires = c.now().Add(c.defaultExpiry)
+	}
+	return c.jitterExpiry(expires)
+}
+
+// WithDefaultExpiry embeds an expiry of d from now in values sealed without
+// one, so every value expires on the server even if the browser is bypassed.
+// This covers cookies Set without a MaxAge or Expires, which the browser keeps
+// until it is closed, as well as values Sealed with a zero expiry and sent in
+// a cookie whose lifetime the Codec does not know. The cookie itself is left
+// unchanged. With this option, no value is sealed as permanent.
+func WithDefaultExpiry(d time.Duration) Option {
+	return func(c *Codec) error {
+		if d <= 0 {
+			return errors.New("sookie: default expiry must be positive")
+		}
+		c.defaultExpiry = d
+		return nil
+	}
+}
+
+// WithMaxDecompressedSize limits the size a value may decompress to when it is
+// opened, which guards against decompression bombs should the secret leak.
+// Values exceeding it fail to open with the ErrDecompressedSize error.
+// It defaults to DefaultMaxDecompressedSize.
+func WithMaxDecompressedSize(max int) Option {
+	return func(c *Codec) error {
+		if max <= 0 {
+			return errors.New("sookie: max decompressed size must be positive")
+		}
+		c.maxDecompressedSize = max
+		return nil
+	}
+}
+
+// WithNonceSource configures the NonceSource used for each sealed value.
+// It defaults to reading from crypto/rand. See CounterNonceSource for a
+// deterministic alternative.
+func WithNonceSource(source NonceSource) Option {
+	return func(c *Codec) error {
+		if source == nil {
+			return errors.New("sookie: nonce source must not be nil")
+		}
+		c.nonceSource = source
+		return nil
+	}
+}
+
+// WithRandReader configures the reader nonces are read from, instead of
+// crypto/rand. With a fixed reader sealing becomes deterministic, which is
+// useful for golden tests, such as using sookietest.RandReader, but a reader
+// which is not cryptographically random, or repeats a nonce for the same
+// secret, breaks the security of the encryption. It must only be used in
+// tests, and production code should never take the reader from its
+// configuration, so using any reader other than crypto/rand.Reader logs a
+// warning, once per process, using the default slog.Logger. The reader must be
+// safe for concurrent use if the Codec is.
+func WithRandReader(r io.Reader) Option {
+	return func(c *Codec) error {
+		if r == nil {
+			return errors.New("sookie: rand reader must not be nil")
+		}
+		if r != rand.Reader {
+			warnRandReader.Do(func() {
+				slog.Warn("sookie: nonces are read from a custom reader, which must only be used in tests")
+			})
+		}
+		c.nonceSource = func(nonce []byte) error {
+			_, err := io.ReadFull(r, nonce)
+			return err
+		}
+		return nil
+	}
+}
+
+// warnRandReader logs the warning about a custom rand reader only once.
+var warnRandReader sync.Once
+
+func randNonce(nonce []byte) error {
+	_, err := rand.Read(nonce)
+	return err
+}
+
+// CounterNonceSource returns a NonceSource which uses a random 16 byte prefix
+// chosen once, followed by a 64-bit big-endian counter.
+// Nonces from a single source never repeat, and the random prefix makes
+// collisions between sources, such as across processes, as unlikely as fully
+// random nonces. An error is returned once the counter is exhausted.
+func CounterNonceSource() (NonceSource, error) {
+	prefix := make([]byte, NonceSize-8)
+	if _, err := rand.Read(prefix); err != nil {
+		return nil, fmt.Errorf("sookie: failed to read nonce prefix: %w", err)
+	}
+	var counter atomic.Uint64
+	return func(nonce []byte) error {
+		n := counter.Add(1)
+		if n == 0 {
+			counter.Store(^uint64(0))
+			return errors.New("sookie: nonce counter exhausted")
+		}
+		copy(nonce, prefix)
+		binary.BigEndian.PutUint64(nonce[len(prefix):], n)
+		return nil
+	}, nil
+}
+
+// Seal encodes a value like the package level Seal function.
+func (c *Codec) Seal(expires time.Time, value any) (string, error) {
+	return c.sealExact(context.Background(), c.sealExpiry(expires), value)
+}
+
+// CanSeal checks that the value can be marshaled like the package level
+// CanSeal function, using the Marshaler of the Codec.
+func (c *Codec) CanSeal(value any) error {
+	return canSeal(c.marshaler, value)
+}
+
+// canSeal marshals the wrapped value using the Marshaler, discarding the
+// result.
+func canSeal(m Marshaler, value any) error {
+	if _, err := m.Marshal(wrap(value, meta{expiry: -1})); err != nil {
+		return fmt.Errorf("%w: %w", ErrMarshal, err)
+	}
+	return nil
+}
+
+// SealNoCompress seals a value like the package level SealNoCompress
+// function.
+func (c *Codec) SealNoCompress(expires time.Time, value any) (string, error) {
+	nc := *c
+	nc.compressor = noCompression
+	return nc.Seal(expires, value)
+}
+
+// sealExact seals the value with the expiry as given, without jitter.
+// The context is checked before the expensive steps of sealing.
+func (c *Codec) sealExact(ctx context.Context, expires time.Time, value any) (encoded string, err error) {
+	done := c.trace(ctx, "seal")
+	defer func() { done(err) }()
+
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	msgp, err := c.marshal(expires, value)
+	if err != nil {
+		return "", err
+	}
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	return c.seal(msgp, nil)
+}
+
+// SealWithStats encodes a value like the package level SealWithStats function.
+func (c *Codec) SealWithStats(expires time.Time, value any) (string, Stats, error) {
+	var stats Stats
+	msgp, err := c.marshal(c.sealExpiry(expires), value)
+	if err != nil {
+		return "", stats, err
+	}
+	sealed, err := c.seal(msgp, &stats)
+	return sealed, stats, err
+}
+
+// EstimateSize returns the length of the sealed value like the package level
+// EstimateSize function, taking the options of the Codec into account.
+func (c *Codec) EstimateSize(value any) (int, error) {
+	_, stats, err := c.SealWithStats(c.now().Add(365*24*time.Hour), value)
+	return stats.Encoded, err
+}
+
+// Open unmarshals the raw value into the value pointed to by dst, like the
+// package level OpenInto function.
+func (c *Codec) Open(raw string, dst any) error {
+	_, err := c.openInto(context.Background(), raw, dst)
+	return c.report("", err)
+}
+
+// OpenWithMetadata unmarshals the raw value into the value pointed to by dst
+// and returns its Metadata, like the package level OpenWithMetadata function.
+func (c *Codec) OpenWithMetadata(raw string, dst any) (Metadata, error) {
+	m, err := c.openInto(context.Background(), raw, dst)
+	md := m.metadata()
+	md.Label = c.label
+	return md, c.report("", err)
+}
+
+// openInto opens the raw value into the value pointed to by dst, returning
+// the metadata stored with it. The context is checked before the expensive steps of
+// opening.
+func (c *Codec) openInto(ctx context.Context, raw string, dst any) (m meta, err error) {
+	done := c.trace(ctx, "open")
+	defer func() { done(err) }()
+
+	if err := checkDst(dst); err != nil {
+		return m, err
+	}
+	if err := ctx.Err(); err != nil {
+		return m, err
+	}
+	cache := openCacheFrom(ctx)
+	var key openKey
+	if cache != nil {
+		key = openKey{aead: c.aead, name: c.name, raw: raw, dst: reflect.TypeOf(dst)}
+		if m, ok := cache.load(key, dst); ok {
+			return m, nil
+		}
+	}
+	uncompressed, err := c.open(raw)
+	if err != nil {
+		return m, err
+	}
+	if err := ctx.Err(); err != nil {
+		return m, err
+	}
+	if m, err = c.unmarshalInto(uncompressed, dst); err == nil && cache != nil {
+		cache.store(key, dst, m)
+	}
+	return m, err
+}
+
+// OpenRaw returns the marshaled wrapper and expiry like the package level
+// OpenRaw function.
+func (c *Codec) OpenRaw(raw string) ([]byte, time.Time, error) {
+	uncompressed, err := c.open(raw)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	m, err := c.expiry(uncompressed)
+	if err != nil {
+		return uncompressed, time.Time{}, err
+	}
+	err = c.checkExpiry(m)
+	if m.expiry == -1 {
+		return uncompressed, time.Time{}, err
+	}
+	return uncompressed, time.Unix(m.expiry, 0), err
+}
+
+// Valid checks the raw value like the package level Valid function.
+func (c *Codec) Valid(raw string) error {
+	_, err := c.openExpiry(raw)
+	return err
+}
+
+// Has reports if the request has a valid cookie like the package level Has
+// function.
+func (c *Codec) Has(r *http.Request, name string) bool {
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return false
+	}
+	return c.forName(name).Valid(cookie.Value) == nil
+}
+
+// ValidateBatch checks many raw values like the package level ValidateBatch
+// function.
+func (c *Codec) ValidateBatch(raws []string) []error {
+	errs := make([]error, len(raws))
+	for i, raw := range raws {
+		errs[i] = c.Valid(raw)
+	}
+	return errs
+}
+
+// RemainingTTL returns the time left until the raw value expires like the
+// package level RemainingTTL function. The maximum lifetime and idle timeout,
+// if any, also limit the remaining time, but the grace period does not extend
+// it.
+func (c *Codec) RemainingTTL(raw string) (time.Duration, error) {
+	uncompressed, err := c.open(raw)
+	if err != nil {
+		return 0, err
+	}
+	m, err := c.expiry(uncompressed)
+	if err != nil {
+		return 0, err
+	}
+	if err := c.checkExpiry(m); err != nil {
+		return 0, err
+	}
+	e := c.deadline(m)
+	if e == -1 {
+		return Permanent, nil
+	}
+	return max(time.Duration(e-c.now().Unix())*time.Second, 0), nil
+}
+
+// Rewrap re-encrypts a raw value sealed by from using this Codec, like the
+// package level Rewrap function. The marshaled value is kept as is, so both
+// Codecs must use the same Marshaler, or the ErrFormat error is returned.
+func (c *Codec) Rewrap(from *Codec, raw string) (string, error) {
+	if from.marshaler.ID() != c.marshaler.ID() {
+		return "", fmt.Errorf("%w: marshaler %d can not rewrap values of marshaler %d",
+			ErrFormat, c.marshaler.ID(), from.marshaler.ID())
+	}
+	uncompressed, err := from.openExpiry(raw)
+	if err != nil {
+		return "", err
+	}
+	return c.seal(uncompressed, nil)
+}
+
+// Set sets a cookie with the given value like the package level Set function.
+func (c *Codec) Set(w http.ResponseWriter, value any, cookie http.Cookie) error {
+	_, err := c.SetWithSize(w, value, cookie)
+	return err
+}
+
+// SetCtx sets a cookie with the given value like the package level SetCtx function.
+func (c *Codec) SetCtx(ctx context.Context, w http.ResponseWriter, value any, cookie http.Cookie) error {
+	_, err := c.set(ctx, w, value, cookie)
+	return err
+}
+
+// SetWithSize sets a cookie like the package level SetWithSize function.
+func (c *Codec) SetWithSize(w http.ResponseWriter, value any, cookie http.Cookie) (int, error) {
+	return c.set(context.Background(), w, value, cookie)
+}
+
+// set seals the value into the cookie and adds it to the response,
+// returning the length of the Set-Cookie header value.
+func (c *Codec) set(ctx context.Context, w http.ResponseWriter, value any, cookie http.Cookie) (int, error) {
+	if err := checkWritten(w); err != nil {
+		return 0, err
+	}
+	sealed, err := c.sealCookie(ctx, value, cookie)
+	if err != nil {
+		return 0, err
+	}
+	if err := c.checkTotalSize(w, sealed); err != nil {
+		return 0, err
+	}
+	return setCookie(w, sealed), nil
+}
+
+// SealCookie seals the value into the cookie like the package level
+// SealCookie function.
+func (c *Codec) SealCookie(value any, cookie http.Cookie) (string, error) {
+	sealed, err := c.MakeCookie(value, cookie)
+	if err != nil {
+		return "", err
+	}
+	return sealed.String(), nil
+}
+
+// MakeCookie seals the value into a copy of the template like the package
+// level MakeCookie function.
+func (c *Codec) MakeCookie(value any, template http.Cookie) (*http.Cookie, error) {
+	return c.sealCookie(context.Background(), value, template)
+}
+
+// sealCookie seals the value into the cookie, applying the same checks and
+// expiry handling as Set.
+func (c *Codec) sealCookie(ctx context.Context, value any, cookie http.Cookie) (*http.Cookie, error) {
+	if cookie.Value != "" {
+		return nil, ErrValueMustBeEmpty
+	}
+	if err := checkPrefix(&cookie); err != nil {
+		return nil, err
+	}
+	if err := c.checkSecurity(&cookie); err != nil {
+		return nil, err
+	}
+
+	// special case delete cookie
+	if cookie.MaxAge < 0 {
+		return &cookie, nil
+	}
+
+	// jitter the browser and embedded expiry the same way so they agree
+	var expires time.Time
+	if cookie.MaxAge > 0 {
+		cookie.MaxAge += int(c.jitter() / time.Second)
+		expires = c.now().Add(time.Duration(cookie.MaxAge) * time.Second)
+	} else if !cookie.Expires.IsZero() {
+		cookie.Expires = c.jitterExpiry(cookie.Expires)
+		expires = cookie.Expires
+	} else {
+		// a browser session cookie, which only expires if there is a default
+		expires = c.sealExpiry(expires)
+	}
+	return c.sealInto(ctx, value, expires, cookie)
+}
+
+// SetUntil sets a cookie with the given value and absolute expiry like the
+// package level SetUntil function.
+func (c *Codec) SetUntil(w http.ResponseWriter, value any, expires time.Time, cookie http.Cookie) error {
+	if cookie.Value != "" {
+		return ErrValueMustBeEmpty
+	}
+	if err := checkWritten(w); err != nil {
+		return err
+	}
+	if err := checkPrefix(&cookie); err != nil {
+		return err
+	}
+	if err := c.checkSecurity(&cookie); err != nil {
+		return err
+	}
+	if expires.IsZero() {
+		return errors.New("sookie: expiry must not be zero")
+	}
+	expires = c.jitterExpiry(expires)
+	cookie.MaxAge = MaxAge(expires.Sub(c.now()))
+	cookie.Expires = time.Time{}
+	if cookie.MaxAge <= 0 {
+		return ErrExpired
+	}
+	sealed, err := c.sealInto(context.Background(), value, expires, cookie)
+	if err != nil {
+		return err
+	}
+	if err := c.checkTotalSize(w, sealed); err != nil {
+		return err
+	}
+	setCookie(w, sealed)
+	return nil
+}
+
+// MaxAge returns the cookie MaxAge for a value expiring after d, rounded down
+// to whole seconds so the browser never keeps the cookie longer than the
+// embedded expiry allows.
+func MaxAge(d time.Duration) int {
+	return int(d / time.Second)
+}
+
+// sealInto seals the value with the given expiry into the cookie, and checks
+// the resulting cookie is valid.
+func (c *Codec) sealInto(ctx context.Context, value any, expires time.Time, cookie http.Cookie) (*http.Cookie, error) {
+	encoded, err := c.forName(cookie.Name).sealExact(ctx, expires, value)
+	if err != nil {
+		return nil, err
+	}
+	cookie.Value = encoded
+
+	if err := cookie.Valid(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCookie, err)
+	}
+	return &cookie, nil
+}
+
+// setCookie adds the Set-Cookie header like http.SetCookie, returning the
+// length of the header value. A Set-Cookie header already in the response for
+// the same cookie, one with the same Name, Path and Domain, is replaced instead
+// of sending conflicting headers.
+func setCookie(w http.ResponseWriter, cookie *http.Cookie) int {
+	v := cookie.String()
+	if v == "" {
+		return 0
+	}
+	h := w.Header()
+	for i, existing := range h["Set-Cookie"] {
+		if sameCookie(existing, cookie) {
+			h["Set-Cookie"][i] = v
+			return len(v)
+		}
+	}
+	h.Add("Set-Cookie", v)
+	return len(v)
+}
+
+// pendingCookie reports if a Set-Cookie header for the same cookie is already
+// in the response.
+func pendingCookie(w http.ResponseWriter, cookie *http.Cookie) bool {
+	for _, existing := range w.Header()["Set-Cookie"] {
+		if sameCookie(existing, cookie) {
+			return true
+		}
+	}
+	return false
+}
+
+// sameCookie reports if the Set-Cookie header value sets the same cookie as
+// the given one. Browsers identify a cookie by its Name, Path and Domain.
+func sameCookie(header string, cookie *http.Cookie) bool {
+	existing, err := http.ParseSetCookie(header)
+	if err != nil {
+		return false
+	}
+	return existing.Name == cookie.Name &&
+		existing.Path == cookie.Path &&
+		strings.EqualFold(existing.Domain, strings.TrimPrefix(cookie.Domain, "."))
+}
+
+// List returns the names of the cookies in the request which look like values
+// sealed by this Codec, like the package level List function, taking the value
+// prefix and encoding into account. Signed and debug values are only listed if
+// the Codec uses WithSignOnly or WithDebugPlaintext. For a LabeledKeyring, use
+// the Codec of each label.
+func (c *Codec) List(r *http.Request) []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, cookie := range r.Cookies() {
+		if seen[cookie.Name] || !c.looksSealed(cookie.Value) {
+			continue
+		}
+		seen[cookie.Name] = true
+		names = append(names, cookie.Name)
+	}
+	return names
+}
+
+// looksSealed reports if the raw value is structurally a value sealed by the
+// Codec.
+func (c *Codec) looksSealed(raw string) bool {
+	raw, err := c.cutPrefix(raw)
+	if err != nil || c.encoding.DecodedLen(len(raw)) < headerSize {
+		return false
+	}
+	message, err := c.encoding.DecodeString(raw)
+	if err != nil || len(message) < headerSize {
+		return false
+	}
+	switch message[0] {
+	case formatVersion:
+		return len(message) >= minMessageSize
+	case formatSigned:
+		return c.signOnly && len(message) >= headerSize+sha256.Size
+	case formatDebug:
+		return c.debug
+	}
+	return false
+}
+
+// Get retrieves a cookie with the given name from the request and unmarshals
+// it into the value pointed to by dst, like the package level Get function.
+func (c *Codec) Get(r *http.Request, name string, dst any) error {
+	return c.GetCtx(context.Background(), r, name, dst)
+}
+
+// GetCtx is like Get, but uses the context for cancellation and tracing, like
+// the package level GetCtx function.
+func (c *Codec) GetCtx(ctx context.Context, r *http.Request, name string, dst any) error {
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		if err != http.ErrNoCookie {
+			err = fmt.Errorf("sookie: failed to get cookie: %w", err)
+		}
+		return c.report(name, err)
+	}
+	return c.report(name, c.openCookie(ctx, name, cookie.Value, dst))
+}
+
+// GetAndDelete retrieves a cookie and deletes it from the response like the
+// package level GetAndDelete function.
+func (c *Codec) GetAndDelete(w http.ResponseWriter, r *http.Request, cookie http.Cookie, dst any) error {
+	err := c.Get(r, cookie.Name, dst)
+	Del(w, r, cookie)
+	return err
+}
+
+// GetFromCookies retrieves a cookie with the given name from a list of cookies
+// like the package level GetFromCookies function.
+func (c *Codec) GetFromCookies(cookies []*http.Cookie, name string, dst any) error {
+	for _, cookie := range cookies {
+		if cookie.Name == name {
+			return c.report(name, c.openCookie(context.Background(), name, cookie.Value, dst))
+		}
+	}
+	return c.report(name, http.ErrNoCookie)
+}
+
+// GetAny is like Get, but tries every cookie with the given name in the
+// request, like the package level GetAny function.
+func (c *Codec) GetAny(r *http.Request, name string, dst any) error {
+	var first error
+	for _, cookie := range r.CookiesNamed(name) {
+		err := c.openCookie(context.Background(), name, cookie.Value, dst)
+		if err == nil {
+			return nil
+		}
+		if first == nil {
+			first = err
+		}
+	}
+	if first == nil {
+		return c.report(name, http.ErrNoCookie)
+	}
+	return c.report(name, first)
+}
+
+// report calls the error hook, if any, for a failure to open the named
+// cookie, returning the error.
+func (c *Codec) report(name string, err error) error {
+	if err == nil || c.errorHook == nil {
+		return err
+	}
+	if err == http.ErrNoCookie && !c.hookNoCookie {
+		return err
+	}
+	c.errorHook(name, err)
+	return err
+}
+
+// openCookie opens a cookie value into dst, falling back to the legacy
+// decoder if the value is not structurally a sealed value.
+func (c *Codec) openCookie(ctx context.Context, name, raw string, dst any) error {
+	_, err := c.forName(name).openInto(ctx, raw, dst)
+	if err == nil || c.legacyDecoder == nil {
+		return err
+	}
+	if errors.Is(err, ErrDecode) || errors.Is(err, ErrInvalidLength) || errors.Is(err, ErrFormat) {
+		if c.legacyDecoder(raw, dst) == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// marshal wraps the value with its expiry and marshals it.
+func (c *Codec) marshal(expires time.Time, value any) ([]byte, error) {
+	var e int64 = -1
+	if !expires.IsZero() {
+		e = expires.Unix()
+	}
+
+	m := meta{expiry: e, created: c.now().Unix(), idle: c.idleTimeout, tag: c.tag}
+	if c.preciseExpiry && e != -1 {
+		m.expiryMilli = expires.UnixMilli()
+	}
+	if c.replay != nil {
+		m.id = make([]byte, replayIDSize)
+		if _, err := rand.Read(m.id); err != nil {
+			return nil, fmt.Errorf("sookie: failed to read replay ID: %w", err)
+		}
+	}
+	began := c.stageStart()
+	msgp, err := c.marshaler.Marshal(wrap(value, m))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMarshal, err)
+	}
+	c.stageDone("marshal", began)
+	return msgp, nil
+}
+
+// bufPool holds buffers reused while sealing, to reduce allocations.
+var bufPool = sync.Pool{New: func() any { return new([]byte) }}
+
+// maxPooledSize is the capacity of the largest buffer returned to bufPool, so
+// a single large value does not keep a large buffer alive.
+const maxPooledSize = 64 << 10
+
+// getBuf returns an empty buffer from bufPool.
+func getBuf() *[]byte {
+	return bufPool.Get().(*[]byte)
+}
+
+// putBuf returns a buffer to bufPool. The buffer must not be used afterwards.
+func putBuf(b *[]byte) {
+	if cap(*b) > maxPooledSize {
+		return
+	}
+	*b = (*b)[:0]
+	bufPool.Put(b)
+}
+
+// seal compresses, encrypts and encodes a marshaled wrapper, filling in stats if it is not nil.
+func (c *Codec) seal(msgp []byte, stats *Stats) (string, error) {
+	buf := getBuf()
+	defer putBuf(buf)
+	message, err := c.sealBytes(*buf, msgp, stats)
+	if err != nil {
+		return "", err
+	}
+	// the prefix and encoding are appended after the message, reusing the
+	// same buffer
+	began := c.stageStart()
+	*buf = append(message, c.prefix...)
+	*buf = c.encoding.AppendEncode(*buf, message)
+	encoded := string((*buf)[len(message):])
+	c.stageDone("encode", began)
+	if stats != nil {
+		stats.Encoded = len(encoded)
+	}
+	return encoded, nil
+}
+
+// sealBytes compresses and encrypts a marshaled wrapper, filling in stats if it is not nil.
+// The message is appended to dst, and is the header, followed by the nonce and the ciphertext.
+func (c *Codec) sealBytes(dst, msgp []byte, stats *Stats) ([]byte, error) {
+	if c.debug {
+		if stats != nil {
+			stats.Marshaled = len(msgp)
+			stats.Compressed = len(msgp)
+		}
+		dst = append(dst, formatDebug, c.marshaler.ID())
+		return append(dst, msgp...), nil
+	}
+	if c.signOnly {
+		return c.sealSigned(dst, msgp, stats), nil
+	}
+	buf := getBuf()
+	defer putBuf(buf)
+	h := header{
+		marshaler:  c.marshaler.ID(),
+		outerMAC:   c.macKey != nil,
+		versioned:  c.versioned,
+		keyVersion: c.keyVersion,
+		dictID:     c.dictVersion,
+		cleartext:  c.cleartext,
+	}
+	began := c.stageStart()
+	h.compression, msgp = c.compress(buf, msgp, stats)
+	if c.compressor != noCompression {
+		c.stageDone("compress", began)
+	}
+	if c.blockSize > 0 {
+		h.padded = true
+		msgp = pad(msgp, c.blockSize)
+	}
+
+	// capacity for the whole message, the ciphertext is appended in place
+	began = c.stageStart()
+	dst = slices.Grow(dst, h.size()+NonceSize+len(msgp)+c.aead.Overhead()+macSize)
+	start := len(dst)
+	dst = h.appendTo(dst)
+	dst = dst[:start+h.size()+NonceSize]
+	nonce := dst[start+h.size():]
+	if err := c.nonceSource(nonce); err != nil {
+		return nil, fmt.Errorf("sookie: failed to read nonce: %w", err)
+	}
+	dst = c.aead.Seal(dst, nonce, msgp, c.additionalData(dst[start:start+h.size()]))
+	if h.outerMAC {
+		dst = append(dst, mac(c.macKey, dst[start:])...)
+	}
+	c.stageDone("encrypt", began)
+	return dst, nil
+}
+
+// compress compresses a marshaled wrapper into buf, filling in stats if it is
+// not nil. Values that do not shrink when compressed, such as already
+// compressed data, are returned as is. The compression algorithm used is
+// returned along with the data.
+func (c *Codec) compress(buf *[]byte, msgp []byte, stats *Stats) (byte, []byte) {
+	compression := byte(c.compressor)
+	switch {
+	case c.compressor == noCompression:
+	case c.compressor == Gzip:
+		*buf = gzipCompress((*buf)[:0], msgp)
+	default:
+		switch {
+		case c.dicts != nil:
+			compression = compressionZstdDictID
+		case len(c.dict) != 0:
+			compression = compressionZstdDict
+		}
+		*buf = c.encoder.EncodeAll(msgp, (*buf)[:0])
+	}
+	compressed := *buf
+	if compression == compressionNone || len(compressed) >= len(msgp) {
+		compression, compressed = compressionNone, msgp
+	}
+	if stats != nil {
+		stats.Marshaled = len(msgp)
+		stats.Compressed = len(compressed)
+	}
+	return compression, compressed
+}
+
+// decompress reverses compress using the compression algorithm from the header.
+func (c *Codec) decompress(h header, plaintext []byte) ([]byte, error) {
+	switch h.compression {
+	case compressionNone:
+		return plaintext, nil
+	case compressionZstdDictID:
+		return c.decompressDict(h.dictID, plaintext)
+	case compressionZstdDict:
+		if len(c.dict) == 0 {
+			return nil, fmt.Errorf("%w: sealed with a compression dictionary", ErrFormat)
+		}
+		fallthrough
+	case compressionZstd:
+		return decodeAll(c.decoder, plaintext)
+	case compressionGzip:
+		return gzipDecompress(plaintext, c.maxDecompressedSize)
+	}
+	return nil, fmt.Errorf("%w: unknown compression %d", ErrFormat, h.compression)
+}
+
+// decodeAll decompresses a whole Zstandard frame using the decoder.
+func decodeAll(d *zstd.Decoder, plaintext []byte) ([]byte, error) {
+	uncompressed, err := d.DecodeAll(plaintext, nil)
+	if err != nil {
+		if errors.Is(err, zstd.ErrDecoderSizeExceeded) {
+			return nil, fmt.Errorf("%w: %w", ErrDecompressedSize, err)
+		}
+		return nil, fmt.Errorf("%w: %w", ErrDecompress, err)
+	}
+	return uncompressed, nil
+}
+
+// open decodes, decrypts and decompresses a raw value into a marshaled wrapper.
+func (c *Codec) open(raw string) ([]byte, error) {
+	raw, err := c.cutPrefix(raw)
+	if err != nil {
+		return nil, err
+	}
+	began := c.stageStart()
+	message, err := c.encoding.DecodeString(raw)
+	if err != nil {
+		return nil, decodeError(raw, err)
+	}
+	c.stageDone("decode", began)
+	return c.openBytes(message)
+}
+
+// cutPrefix removes the value prefix from the raw value, see WithValuePrefix,
+// after removing any percent-encoding, see WithURLDecodeFirst.
+func (c *Codec) cutPrefix(raw string) (string, error) {
+	if c.urlDecode {
+		var err error
+		if raw, err = urlDecode(raw); err != nil {
+			return "", err
+		}
+	}
+	if c.prefix == "" {
+		return raw, nil
+	}
+	raw, ok := strings.CutPrefix(raw, c.prefix)
+	if !ok {
+		return "", fmt.Errorf("%w: missing prefix %q", ErrFormat, c.prefix)
+	}
+	return raw, nil
+}
+
+// errTooShort is returned when a message is too short to be a sealed value.
+var errTooShort = fmt.Errorf("%w: %w", ErrTruncated, ErrInvalidLength)
+
+// decodeError wraps an error decoding the raw value, also wrapping
+// ErrTruncated if decoding failed in the last few characters, which is where
+// a truncated value fails.
+func decodeError(raw string, err error) error {
+	var corrupt base64.CorruptInputError
+	if errors.As(err, &corrupt) && int(corrupt) >= len(raw)-4 {
+		return fmt.Errorf("%w: %w: %w", ErrTruncated, ErrDecode, err)
+	}
+	return fmt.Errorf("%w: %w", ErrDecode, err)
+}
+
+// openBytes decrypts and decompresses a message of the header, nonce and
+// ciphertext into a marshaled wrapper. The message is decrypted in place.
+func (c *Codec) openBytes(message []byte) ([]byte, error) {
+	if len(message) == 0 || !c.acceptsLegacy() {
+		return c.openFormat(message)
+	}
+	legacy := message
+	if knownFormat(message[0]) {
+		// a legacy message may begin with the same byte, and opening it in the
+		// current format overwrites the ciphertext
+		buf := getBuf()
+		defer putBuf(buf)
+		*buf = append((*buf)[:0], message...)
+		legacy = *buf
+	}
+	plaintext, err := c.openFormat(message)
+	if err != nil {
+		if uncompressed, legacyErr := c.openLegacy(legacy); legacyErr == nil {
+			return uncompressed, nil
+		}
+	}
+	return plaintext, err
+}
+
+// openFormat opens a message in the current format, or one of the debug or
+// signed formats.
+func (c *Codec) openFormat(message []byte) ([]byte, error) {
+	if len(message) != 0 {
+		switch message[0] {
+		case formatDebug:
+			return c.openDebug(message)
+		case formatSigned:
+			return c.openSigned(message)
+		}
+	}
+	if len(message) < minMessageSize {
+		return nil, errTooShort
+	}
+	full := message
+	h, ad, message, err := parseHeader(message)
+	if err != nil {
+		return nil, err
+	}
+	began := c.stageStart()
+	if h.outerMAC {
+		if len(message) < NonceSize+c.aead.Overhead()+macSize {
+			return nil, errTooShort
+		}
+		if c.macKey != nil {
+			if err := verifyMAC(c.macKey, full); err != nil {
+				return nil, err
+			}
+		}
+		message = message[:len(message)-macSize]
+	} else if c.macKey != nil {
+		return nil, ErrOuterMAC
+	}
+	if h.marshaler != c.marshaler.ID() {
+		return nil, fmt.Errorf("%w: sealed with marshaler %d, expected %d",
+			ErrFormat, h.marshaler, c.marshaler.ID())
+	}
+	if c.versioned && h.versioned && h.keyVersion != c.keyVersion {
+		return nil, fmt.Errorf("%w: sealed with key version %d, expected %d",
+			ErrDecrypt, h.keyVersion, c.keyVersion)
+	}
+	if len(message) < NonceSize+c.aead.Overhead() {
+		return nil, errTooShort
+	}
+	nonce, ciphertext := message[:NonceSize], message[NonceSize:]
+	plaintext, err := c.aead.Open(ciphertext[:0], nonce, ciphertext, c.additionalData(ad))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDecrypt, err)
+	}
+	if h.padded {
+		if plaintext, err = unpad(plaintext); err != nil {
+			return nil, err
+		}
+	}
+	c.stageDone("decrypt", began)
+	if h.compression == compressionNone {
+		return plaintext, nil
+	}
+	began = c.stageStart()
+	uncompressed, err := c.decompress(h, plaintext)
+	if err != nil {
+		return nil, err
+	}
+	c.stageDone("decompress", began)
+	return uncompressed, nil
+}
+
+// openDebug returns the marshaled wrapper from a value stored by
+// WithDebugPlaintext, which is only accepted if the Codec uses it too.
+func (c *Codec) openDebug(message []byte) ([]byte, error) {
+	if !c.debug {
+		return nil, fmt.Errorf("%w: unencrypted debug value", ErrFormat)
+	}
+	if len(message) < 2 || message[1] != c.marshaler.ID() {
+		return nil, fmt.Errorf("%w: invalid debug value", ErrFormat)
+	}
+	return message[2:], nil
+}
+
+// openExpiry opens the raw value and checks only the expiry, returning the
+// marshaled wrapper if it has not expired.
+func (c *Codec) openExpiry(raw string) ([]byte, error) {
+	uncompressed, err := c.open(raw)
+	if err != nil {
+		return nil, err
+	}
+	m, err := c.expiry(uncompressed)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.checkExpiry(m); err != nil {
+		return nil, err
+	}
+	return uncompressed, nil
+}
+
+// expiry unmarshals only the metadata from a marshaled wrapper, leaving out
+// the value and replay ID.
+func (c *Codec) expiry(uncompressed []byte) (meta, error) {
+	var w struct {
+		E, C, D, P int64
+		T          string
+	}
+	if err := c.marshaler.Unmarshal(uncompressed, &w); err != nil {
+		return meta{}, c.unmarshalError(uncompressed, err)
+	}
+	return meta{expiry: w.E, created: w.C, idle: w.D, expiryMilli: w.P, tag: w.T}, nil
+}
+
+// checkDst ensures dst is a non-nil pointer that can be unmarshaled into.
+func checkDst(dst any) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("sookie: destination must be a non-nil pointer, got %T", dst)
+	}
+	return nil
+}
+
+// unmarshalInto unmarshals a marshaled wrapper into the value pointed to by
+// dst and checks the expiry and replay protection, returning the metadata
+// stored with the value. The value is populated even if it has expired.
+func (c *Codec) unmarshalInto(uncompressed []byte, dst any) (meta, error) {
+	rv := reflect.ValueOf(dst).Elem()
+	w := reflect.New(wrapperOf(rv.Type())).Elem()
+	began := c.stageStart()
+	if err := c.marshaler.Unmarshal(uncompressed, w.Addr().Interface()); err != nil {
+		return meta{}, c.unmarshalError(uncompressed, err)
+	}
+	c.stageDone("unmarshal", began)
+	if c.strict {
+		if err := c.checkFields(uncompressed, rv.Type()); err != nil {
+			return meta{}, err
+		}
+	}
+	if c.rejectZero && isZero(w.Field(0)) {
+		return meta{}, ErrZeroValue
+	}
+	rv.Set(w.Field(0))
+	m := meta{
+		expiry:      w.Field(1).Int(),
+		created:     w.Field(2).Int(),
+		id:          w.Field(3).Bytes(),
+		idle:        w.Field(4).Int(),
+		expiryMilli: w.Field(5).Int(),
+		tag:         w.Field(6).String(),
+	}
+	if err := c.checkExpiry(m); err != nil {
+		return m, err
+	}
+	return m, c.checkReplay(m.id)
+}
+
+// checkExpiry checks the stored expiry and creation time, returning the
+// ErrExpired error if the value has expired or has been idle for too long, the
+// ErrLifetime error if the times are implausible given the maximum lifetime,
+// or the ErrStale error if it was sealed before the cutoff.
+func (c *Codec) checkExpiry(m meta) error {
+	e, created := m.expiry, m.created
+	if c.notBefore != 0 && created < c.notBefore {
+		return ErrStale
+	}
+	if c.maxLifetime > 0 {
+		if created == 0 {
+			return fmt.Errorf("%w: no creation time", ErrLifetime)
+		}
+		if created > c.now().Add(c.grace).Unix() {
+			return fmt.Errorf("%w: created in the future", ErrLifetime)
+		}
+		limit := created + int64(c.maxLifetime/time.Second)
+		if e > limit {
+			return fmt.Errorf("%w: expiry beyond the maximum lifetime", ErrLifetime)
+		}
+		if c.expired(limit) {
+			return ErrExpired
+		}
+	}
+	if idle := c.idle(m); idle > 0 && c.expired(created+idle) {
+		return ErrExpired
+	}
+	if c.expired(e) {
+		return ErrExpired
+	}
+	if m.expiryMilli != 0 && c.now().Add(-c.grace).UnixMilli() > m.expiryMilli {
+		return ErrExpired
+	}
+	return nil
+}
+
+// idle returns the idle timeout in seconds stored in the value, or the one
+// configured on the Codec for values sealed without one, or 0 if there is
+// none.
+func (c *Codec) idle(m meta) int64 {
+	if m.idle > 0 {
+		return m.idle
+	}
+	return c.idleTimeout
+}
+
+// keepIdle returns the Codec to seal the value again with, which is a copy
+// using the idle timeout stored in the value if it differs from its own.
+func (c *Codec) keepIdle(m meta) *Codec {
+	if m.idle == 0 || m.idle == c.idleTimeout {
+		return c
+	}
+	nc := *c
+	nc.idleTimeout = m.idle
+	return &nc
+}
+
+// deadline returns the time in Unix seconds after which the value is no
+// longer valid, taking the maximum lifetime and idle timeout into account,
+// or -1 if it never expires.
+func (c *Codec) deadline(m meta) int64 {
+	e := m.expiry
+	limit := func(l int64) {
+		if e == -1 || l < e {
+			e = l
+		}
+	}
+	if c.maxLifetime > 0 {
+		limit(m.created + int64(c.maxLifetime/time.Second))
+	}
+	if idle := c.idle(m); idle > 0 {
+		limit(m.created + idle)
+	}
+	return e
+}
+
+// expired reports if the stored expiry, extended by the grace period, is in
+// the past. An expiry of -1 never expires.
+func (c *Codec) expired(e int64) bool {
+	return e != -1 && c.now().Add(-c.grace).Unix() > e
+}
+
+// wrapper holds the value along with its metadata. Only V and E are always
+// marshaled, the other fields are left out when zero so values sealed without
+// the options using them do not pay for them.
+type wrapper[V any] struct {
+	V V
+	E int64
+	C int64  `msgpack:",omitempty" json:",omitempty"`
+	I []byte `msgpack:",omitempty" json:",omitempty"`
+	D int64  `msgpack:",omitempty" json:",omitempty"`
+	P int64  `msgpack:",omitempty" json:",omitempty"`
+	T string `msgpack:",omitempty" json:",omitempty"`
+}
+
+// omitEmpty is the tag of the optional fields of the wrapper.
+const omitEmpty = `msgpack:",omitempty" json:",omitempty"`
+
+// meta is the metadata stored in the wrapper along with the value.
+type meta struct {
+	// expiry is the expiry in Unix seconds, or -1 if it never expires.
+	expiry int64
+	// created is the time the value was sealed in Unix seconds, or 0 if it
+	// was sealed before creation times were stored.
+	created int64
+	// id is the unique ID used for replay protection, if any.
+	id []byte
+	// idle is the idle timeout in seconds, or 0 if there is none.
+	idle int64
+	// expiryMilli is the expiry in Unix milliseconds, or 0 if it was not
+	// stored, see WithPreciseExpiry.
+	expiryMilli int64
+	// tag identifies the type of the value, see SealTagged.
+	tag string
+}
+
+// wrap returns the wrapper for a value, typed by the dynamic type of the value
+// so it marshals identically to wrapper[V].
+func wrap(value any, m meta) any {
+	if value == nil {
+		return wrapper[any]{E: m.expiry, C: m.created, I: m.id, D: m.idle, P: m.expiryMilli, T: m.tag}
+	}
+	w := reflect.New(wrapperOf(reflect.TypeOf(value))).Elem()
+	w.Field(0).Set(reflect.ValueOf(value))
+	w.Field(1).SetInt(m.expiry)
+	w.Field(2).SetInt(m.created)
+	w.Field(3).SetBytes(m.id)
+	w.Field(4).SetInt(m.idle)
+	w.Field(5).SetInt(m.expiryMilli)
+	w.Field(6).SetString(m.tag)
+	return w.Interface()
+}
+
+var wrapperTypes sync.Map // map[reflect.Type]reflect.Type
+
+// wrapperOf returns the type of wrapper[V] for a V only known at runtime.
+// It marshals identically to the generic wrapper.
+func wrapperOf(t reflect.Type) reflect.Type {
+	if wt, ok := wrapperTypes.Load(t); ok {
+		return wt.(reflect.Type)
+	}
+	wt := reflect.StructOf([]reflect.StructField{
+		{Name: "V", Type: t},
+		{Name: "E", Type: reflect.TypeFor[int64]()},
+		{Name: "C", Type: reflect.TypeFor[int64](), Tag: omitEmpty},
+		{Name: "I", Type: reflect.TypeFor[[]byte](), Tag: omitEmpty},
+		{Name: "D", Type: reflect.TypeFor[int64](), Tag: omitEmpty},
+		{Name: "P", Type: reflect.TypeFor[int64](), Tag: omitEmpty},
+		{Name: "T", Type: reflect.TypeFor[string](), Tag: omitEmpty},
+	})
+	wrapperTypes.Store(t, wt)
+	return wt
+}
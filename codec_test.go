@@ -0,0 +1,892 @@
+package sookie_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daaku/ensure"
+	"github.com/daaku/sookie"
+)
+
+func TestCodecSetGet(t *testing.T) {
+	c, err := sookie.New(secret)
+	ensure.Nil(t, err)
+	w := httptest.NewRecorder()
+	ensure.Nil(t, c.Set(w, given, http.Cookie{Name: cookieName}))
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Cookie", w.Header().Get("Set-Cookie"))
+	var actual Flash
+	ensure.Nil(t, c.Get(r, cookieName, &actual))
+	ensure.DeepEqual(t, actual, given)
+}
+
+func TestCodecInteropWithPackageFunctions(t *testing.T) {
+	c, err := sookie.New(secret)
+	ensure.Nil(t, err)
+	raw, err := c.Seal(time.Time{}, given)
+	ensure.Nil(t, err)
+	actual, err := sookie.Open[Flash](secret, raw)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, actual, given)
+}
+
+func TestNewErrorWithInvalidSecret(t *testing.T) {
+	_, err := sookie.New([]byte("hello world"))
+	ensure.True(t, errors.Is(err, sookie.ErrAEAD))
+}
+
+func TestWithNonceSource(t *testing.T) {
+	var calls int
+	c, err := sookie.New(secret, sookie.WithNonceSource(func(nonce []byte) error {
+		calls++
+		ensure.DeepEqual(t, len(nonce), sookie.NonceSize)
+		for i := range nonce {
+			nonce[i] = byte(calls)
+		}
+		return nil
+	}))
+	ensure.Nil(t, err)
+	raw, err := c.Seal(time.Time{}, given)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, calls, 1)
+	actual, err := sookie.Open[Flash](secret, raw)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, actual, given)
+}
+
+func TestWithNonceSourceError(t *testing.T) {
+	c, err := sookie.New(secret, sookie.WithNonceSource(func([]byte) error {
+		return errors.New("hsm unavailable")
+	}))
+	ensure.Nil(t, err)
+	_, err = c.Seal(time.Time{}, given)
+	ensure.NotNil(t, err)
+	ensure.StringContains(t, err.Error(), "sookie: failed to read nonce: hsm unavailable")
+}
+
+func TestWithNonceSourceNil(t *testing.T) {
+	_, err := sookie.New(secret, sookie.WithNonceSource(nil))
+	ensure.NotNil(t, err)
+	ensure.StringContains(t, err.Error(), "sookie: nonce source must not be nil")
+}
+
+func TestCounterNonceSource(t *testing.T) {
+	source, err := sookie.CounterNonceSource()
+	ensure.Nil(t, err)
+	first := make([]byte, sookie.NonceSize)
+	second := make([]byte, sookie.NonceSize)
+	ensure.Nil(t, source(first))
+	ensure.Nil(t, source(second))
+	ensure.DeepEqual(t, first[:16], second[:16])
+	ensure.False(t, bytes.Equal(first, second))
+	ensure.DeepEqual(t, second[sookie.NonceSize-1], byte(2))
+
+	c, err := sookie.New(secret, sookie.WithNonceSource(source))
+	ensure.Nil(t, err)
+	raw, err := c.Seal(time.Time{}, given)
+	ensure.Nil(t, err)
+	actual, err := sookie.Open[Flash](secret, raw)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, actual, given)
+}
+
+func TestOpenErrorUnknownVersion(t *testing.T) {
+	raw, err := sookie.Seal(secret, time.Time{}, given)
+	ensure.Nil(t, err)
+	message, err := base64.RawURLEncoding.DecodeString(raw)
+	ensure.Nil(t, err)
+	message[0] = 0xff
+	_, err = sookie.Open[Flash](secret, base64.RawURLEncoding.EncodeToString(message))
+	ensure.True(t, errors.Is(err, sookie.ErrFormat))
+}
+
+func TestOpenErrorTamperedHeader(t *testing.T) {
+	raw, err := sookie.Seal(secret, time.Time{}, given)
+	ensure.Nil(t, err)
+	message, err := base64.RawURLEncoding.DecodeString(raw)
+	ensure.Nil(t, err)
+	message[1] = 1
+	c, err := sookie.New(secret, sookie.WithMarshaler(sookie.Gob))
+	ensure.Nil(t, err)
+	var actual Flash
+	err = c.Open(base64.RawURLEncoding.EncodeToString(message), &actual)
+	ensure.True(t, errors.Is(err, sookie.ErrDecrypt))
+}
+
+func TestMaxDecompressedSize(t *testing.T) {
+	large := Flash{Content: strings.Repeat("a", sookie.DefaultMaxDecompressedSize)}
+	raw, err := sookie.Seal(secret, time.Time{}, large)
+	ensure.Nil(t, err)
+	_, err = sookie.Open[Flash](secret, raw)
+	ensure.True(t, errors.Is(err, sookie.ErrDecompressedSize))
+
+	c, err := sookie.New(secret, sookie.WithMaxDecompressedSize(2*sookie.DefaultMaxDecompressedSize))
+	ensure.Nil(t, err)
+	var actual Flash
+	ensure.Nil(t, c.Open(raw, &actual))
+	ensure.DeepEqual(t, actual, large)
+}
+
+func TestWithMaxDecompressedSizeInvalid(t *testing.T) {
+	_, err := sookie.New(secret, sookie.WithMaxDecompressedSize(0))
+	ensure.NotNil(t, err)
+	ensure.StringContains(t, err.Error(), "sookie: max decompressed size must be positive")
+}
+
+func TestSealSkipsCompressionWhenLarger(t *testing.T) {
+	random := make([]byte, 512)
+	_, err := rand.Read(random)
+	ensure.Nil(t, err)
+	// gob stores the bytes verbatim, leaving nothing to compress
+	c, err := sookie.New(secret, sookie.WithMarshaler(sookie.Gob))
+	ensure.Nil(t, err)
+	raw, stats, err := c.SealWithStats(time.Time{}, random)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, stats.Compressed, stats.Marshaled)
+	var actual []byte
+	ensure.Nil(t, c.Open(raw, &actual))
+	ensure.DeepEqual(t, actual, random)
+}
+
+func TestSealCompressesWhenSmaller(t *testing.T) {
+	value := Flash{Content: strings.Repeat("a", 1024)}
+	raw, stats, err := sookie.SealWithStats(secret, time.Time{}, value)
+	ensure.Nil(t, err)
+	ensure.True(t, stats.Compressed < stats.Marshaled)
+	actual, err := sookie.Open[Flash](secret, raw)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, actual, value)
+}
+
+func TestSealPermanent(t *testing.T) {
+	raw, err := sookie.SealPermanent(secret, given)
+	ensure.Nil(t, err)
+	actual, err := sookie.Open[Flash](secret, raw)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, actual, given)
+	_, expires, err := sookie.OpenRaw(secret, raw)
+	ensure.Nil(t, err)
+	ensure.True(t, expires.IsZero())
+}
+
+func TestSealPermanentOpenedYearsLater(t *testing.T) {
+	raw, err := sookie.SealPermanent(secret, given)
+	ensure.Nil(t, err)
+	expiring, err := sookie.Seal(secret, time.Now().Add(time.Hour), given)
+	ensure.Nil(t, err)
+	later := time.Now().AddDate(20, 0, 0)
+	c, err := sookie.New(secret, sookie.WithClock(func() time.Time { return later }))
+	ensure.Nil(t, err)
+	ensure.Nil(t, c.Valid(raw))
+	ensure.DeepEqual(t, c.Valid(expiring), sookie.ErrExpired)
+}
+
+func TestWithClockSetGetBoundary(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	now := start
+	c, err := sookie.New(secret, sookie.WithClock(func() time.Time { return now }))
+	ensure.Nil(t, err)
+	w := httptest.NewRecorder()
+	ensure.Nil(t, c.Set(w, given, http.Cookie{Name: cookieName, MaxAge: 60}))
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(w.Result().Cookies()[0])
+
+	md, err := c.OpenWithMetadata(w.Result().Cookies()[0].Value, new(Flash))
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, md.Expiry, start.Add(time.Minute))
+
+	var actual Flash
+	now = start.Add(time.Minute)
+	ensure.Nil(t, c.Get(r, cookieName, &actual))
+	ensure.DeepEqual(t, actual, given)
+	now = start.Add(time.Minute + time.Second)
+	ensure.True(t, errors.Is(c.Get(r, cookieName, &actual), sookie.ErrExpired))
+}
+
+func TestWithClockNil(t *testing.T) {
+	_, err := sookie.New(secret, sookie.WithClock(nil))
+	ensure.NotNil(t, err)
+	ensure.StringContains(t, err.Error(), "sookie: clock must not be nil")
+}
+
+func TestWithExpiryJitterSeal(t *testing.T) {
+	c, err := sookie.New(secret, sookie.WithExpiryJitter(time.Hour))
+	ensure.Nil(t, err)
+	expires := time.Now().Add(time.Hour).Truncate(time.Second)
+	seen := map[int64]bool{}
+	for range 20 {
+		raw, err := c.Seal(expires, given)
+		ensure.Nil(t, err)
+		_, actual, err := sookie.OpenRaw(secret, raw)
+		ensure.Nil(t, err)
+		ensure.False(t, actual.Before(expires))
+		ensure.False(t, actual.After(expires.Add(time.Hour)))
+		seen[actual.Unix()] = true
+	}
+	ensure.True(t, len(seen) > 1)
+}
+
+func TestWithExpiryJitterSetMaxAge(t *testing.T) {
+	c, err := sookie.New(secret, sookie.WithExpiryJitter(time.Hour))
+	ensure.Nil(t, err)
+	w := httptest.NewRecorder()
+	ensure.Nil(t, c.Set(w, given, http.Cookie{Name: cookieName, MaxAge: 3600}))
+	cookie := w.Result().Cookies()[0]
+	ensure.True(t, cookie.MaxAge >= 3600 && cookie.MaxAge <= 7200)
+	_, expires, err := sookie.OpenRaw(secret, cookie.Value)
+	ensure.Nil(t, err)
+	drift := time.Until(expires) - time.Duration(cookie.MaxAge)*time.Second
+	ensure.True(t, drift.Abs() <= time.Second)
+}
+
+func TestWithExpiryJitterPermanent(t *testing.T) {
+	c, err := sookie.New(secret, sookie.WithExpiryJitter(time.Hour))
+	ensure.Nil(t, err)
+	raw, err := c.Seal(time.Time{}, given)
+	ensure.Nil(t, err)
+	_, expires, err := sookie.OpenRaw(secret, raw)
+	ensure.Nil(t, err)
+	ensure.True(t, expires.IsZero())
+}
+
+// TestWithRandReaderWarns must run before other tests using WithRandReader,
+// since the warning is only logged once.
+func TestWithRandReaderWarns(t *testing.T) {
+	var logs bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	_, err := sookie.New(secret, sookie.WithRandReader(rand.Reader))
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, logs.String(), "")
+	_, err = sookie.New(secret, sookie.WithRandReader(bytes.NewReader(nil)))
+	ensure.Nil(t, err)
+	ensure.StringContains(t, logs.String(), "must only be used in tests")
+	logs.Reset()
+	_, err = sookie.New(secret, sookie.WithRandReader(bytes.NewReader(nil)))
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, logs.String(), "")
+}
+
+func TestWithRandReaderDeterministic(t *testing.T) {
+	seal := func() string {
+		c, err := sookie.New(secret, sookie.WithRandReader(bytes.NewReader(make([]byte, sookie.NonceSize))))
+		ensure.Nil(t, err)
+		raw, err := c.Seal(time.Time{}, given)
+		ensure.Nil(t, err)
+		return raw
+	}
+	first := seal()
+	ensure.DeepEqual(t, seal(), first)
+	actual, err := sookie.Open[Flash](secret, first)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, actual, given)
+}
+
+func TestWithRandReaderExhausted(t *testing.T) {
+	c, err := sookie.New(secret, sookie.WithRandReader(bytes.NewReader(nil)))
+	ensure.Nil(t, err)
+	_, err = c.Seal(time.Time{}, given)
+	ensure.NotNil(t, err)
+	ensure.StringContains(t, err.Error(), "sookie: failed to read nonce")
+}
+
+func TestSetCtxGetCtx(t *testing.T) {
+	ctx := context.Background()
+	w := httptest.NewRecorder()
+	ensure.Nil(t, sookie.SetCtx(ctx, secret, w, given, http.Cookie{Name: cookieName}))
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Cookie", w.Header().Get("Set-Cookie"))
+	actual, err := sookie.GetCtx[Flash](ctx, secret, r, cookieName)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, actual, given)
+}
+
+func TestSetCtxCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w := httptest.NewRecorder()
+	err := sookie.SetCtx(ctx, secret, w, given, http.Cookie{Name: cookieName})
+	ensure.True(t, errors.Is(err, context.Canceled))
+	ensure.DeepEqual(t, w.Header().Get("Set-Cookie"), "")
+}
+
+func TestGetCtxCanceled(t *testing.T) {
+	w := httptest.NewRecorder()
+	ensure.Nil(t, sookie.Set(secret, w, given, http.Cookie{Name: cookieName}))
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Cookie", w.Header().Get("Set-Cookie"))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := sookie.GetCtx[Flash](ctx, secret, r, cookieName)
+	ensure.True(t, errors.Is(err, context.Canceled))
+}
+
+func TestWithTracer(t *testing.T) {
+	type key struct{}
+	var ops []string
+	var errs []error
+	c, err := sookie.New(secret, sookie.WithTracer(func(ctx context.Context, op string) func(error) {
+		ensure.DeepEqual(t, ctx.Value(key{}), "span")
+		ops = append(ops, op)
+		return func(err error) { errs = append(errs, err) }
+	}))
+	ensure.Nil(t, err)
+	ctx := context.WithValue(context.Background(), key{}, "span")
+	w := httptest.NewRecorder()
+	ensure.Nil(t, c.SetCtx(ctx, w, given, http.Cookie{Name: cookieName}))
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Cookie", w.Header().Get("Set-Cookie"))
+	var actual Flash
+	ensure.Nil(t, c.GetCtx(ctx, r, cookieName, &actual))
+	r.Header.Set("Cookie", cookieName+"=invalid-value-that-is-long-enough-to-be-parsed")
+	ensure.NotNil(t, c.GetCtx(ctx, r, cookieName, &actual))
+	ensure.DeepEqual(t, ops, []string{"seal", "open", "open"})
+	ensure.Nil(t, errs[0])
+	ensure.Nil(t, errs[1])
+	ensure.NotNil(t, errs[2])
+}
+
+func TestWithPadding(t *testing.T) {
+	c, err := sookie.New(secret, sookie.WithPadding(128))
+	ensure.Nil(t, err)
+	small, err := c.Seal(time.Time{}, "a")
+	ensure.Nil(t, err)
+	large, err := c.Seal(time.Time{}, "a slightly longer value")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, len(small), len(large))
+
+	var actual string
+	ensure.Nil(t, c.Open(large, &actual))
+	ensure.DeepEqual(t, actual, "a slightly longer value")
+
+	// opening does not need the padding option
+	actual, err = sookie.Open[string](secret, small)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, actual, "a")
+}
+
+func TestWithPaddingInvalid(t *testing.T) {
+	_, err := sookie.New(secret, sookie.WithPadding(0))
+	ensure.StringContains(t, err.Error(), "padding block size")
+	_, err = sookie.New(secret, sookie.WithPadding(256))
+	ensure.StringContains(t, err.Error(), "padding block size")
+}
+
+// flashDict returns a dictionary of marshaled wrappers holding a Flash.
+func flashDict(t *testing.T) []byte {
+	var dict []byte
+	for _, kind := range []string{"success", "warning", "error"} {
+		b, err := sookie.MsgPack.Marshal(struct {
+			V Flash
+			E int64
+		}{V: Flash{Kind: kind, Content: "Your changes have been saved."}, E: -1})
+		ensure.Nil(t, err)
+		dict = append(dict, b...)
+	}
+	return dict
+}
+
+func TestWithCompressionDict(t *testing.T) {
+	dict := flashDict(t)
+	c, err := sookie.New(secret, sookie.WithCompressionDict(dict))
+	ensure.Nil(t, err)
+	sealed, err := c.Seal(time.Time{}, given)
+	ensure.Nil(t, err)
+	var actual Flash
+	ensure.Nil(t, c.Open(sealed, &actual))
+	ensure.DeepEqual(t, actual, given)
+
+	// values sealed without the dictionary can still be opened
+	plain, err := sookie.Seal(secret, time.Time{}, given)
+	ensure.Nil(t, err)
+	ensure.Nil(t, c.Open(plain, &actual))
+	ensure.DeepEqual(t, actual, given)
+}
+
+func TestWithCompressionDictRequiredToOpen(t *testing.T) {
+	c, err := sookie.New(secret, sookie.WithCompressionDict(flashDict(t)))
+	ensure.Nil(t, err)
+	large := Flash{Kind: "success", Content: strings.Repeat("Your changes have been saved. ", 8)}
+	sealed, err := c.Seal(time.Time{}, large)
+	ensure.Nil(t, err)
+	message, err := base64.RawURLEncoding.DecodeString(sealed)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, message[2], byte(2)) // Zstandard with a dictionary
+	_, err = sookie.Open[Flash](secret, sealed)
+	ensure.True(t, errors.Is(err, sookie.ErrFormat))
+
+	other, err := sookie.New(secret, sookie.WithCompressionDict([]byte("a different dictionary")))
+	ensure.Nil(t, err)
+	var actual Flash
+	ensure.True(t, errors.Is(other.Open(sealed, &actual), sookie.ErrDecompress))
+}
+
+func TestWithCompressionDictEmpty(t *testing.T) {
+	_, err := sookie.New(secret, sookie.WithCompressionDict(nil))
+	ensure.StringContains(t, err.Error(), "dictionary must not be empty")
+}
+
+func legacyJSON(raw string, dst any) error {
+	data, err := base64.StdEncoding.DecodeString(raw)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dst)
+}
+
+func TestWithLegacyDecoder(t *testing.T) {
+	c, err := sookie.New(secret, sookie.WithLegacyDecoder(legacyJSON))
+	ensure.Nil(t, err)
+	legacy, err := json.Marshal(given)
+	ensure.Nil(t, err)
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(&http.Cookie{Name: cookieName, Value: base64.StdEncoding.EncodeToString(legacy)})
+	var actual Flash
+	ensure.Nil(t, c.Get(r, cookieName, &actual))
+	ensure.DeepEqual(t, actual, given)
+}
+
+func TestWithLegacyDecoderNotUsedForDecryptErrors(t *testing.T) {
+	called := false
+	c, err := sookie.New(secret, sookie.WithLegacyDecoder(func(raw string, dst any) error {
+		called = true
+		return nil
+	}))
+	ensure.Nil(t, err)
+	other, err := sookie.Seal(bytes.Repeat([]byte("b"), 32), time.Time{}, given)
+	ensure.Nil(t, err)
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(&http.Cookie{Name: cookieName, Value: other})
+	var actual Flash
+	ensure.True(t, errors.Is(c.Get(r, cookieName, &actual), sookie.ErrDecrypt))
+	ensure.False(t, called)
+}
+
+func TestWithLegacyDecoderFails(t *testing.T) {
+	c, err := sookie.New(secret, sookie.WithLegacyDecoder(legacyJSON))
+	ensure.Nil(t, err)
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(&http.Cookie{Name: cookieName, Value: "garbage"})
+	var actual Flash
+	ensure.True(t, errors.Is(c.Get(r, cookieName, &actual), sookie.ErrInvalidLength))
+}
+
+func TestWithErrorHook(t *testing.T) {
+	var names []string
+	var errs []error
+	c, err := sookie.New(secret, sookie.WithErrorHook(func(name string, err error) {
+		names = append(names, name)
+		errs = append(errs, err)
+	}))
+	ensure.Nil(t, err)
+	var actual Flash
+	r := httptest.NewRequest("GET", "/", nil)
+	ensure.True(t, errors.Is(c.Get(r, cookieName, &actual), http.ErrNoCookie))
+	ensure.DeepEqual(t, len(names), 0)
+
+	r.AddCookie(&http.Cookie{Name: cookieName, Value: "garbage"})
+	ensure.NotNil(t, c.Get(r, cookieName, &actual))
+	ensure.NotNil(t, c.Open("garbage", &actual))
+	ensure.DeepEqual(t, names, []string{cookieName, ""})
+	ensure.True(t, errors.Is(errs[0], sookie.ErrInvalidLength))
+}
+
+func TestWithErrorHookNoCookie(t *testing.T) {
+	var names []string
+	c, err := sookie.New(secret,
+		sookie.WithErrorHook(func(name string, err error) {
+			ensure.True(t, errors.Is(err, http.ErrNoCookie))
+			names = append(names, name)
+		}),
+		sookie.WithErrorHookNoCookie(true))
+	ensure.Nil(t, err)
+	var actual Flash
+	r := httptest.NewRequest("GET", "/", nil)
+	ensure.NotNil(t, c.Get(r, cookieName, &actual))
+	ensure.NotNil(t, c.GetAny(r, cookieName, &actual))
+	ensure.DeepEqual(t, names, []string{cookieName, cookieName})
+}
+
+func TestWithExpiryGrace(t *testing.T) {
+	now := time.Now()
+	sealed, err := sookie.Seal(secret, now.Add(time.Minute), given)
+	ensure.Nil(t, err)
+	clock := func() time.Time { return now.Add(time.Minute + 10*time.Second) }
+
+	strict, err := sookie.New(secret, sookie.WithClock(clock))
+	ensure.Nil(t, err)
+	var actual Flash
+	ensure.True(t, errors.Is(strict.Open(sealed, &actual), sookie.ErrExpired))
+
+	lenient, err := sookie.New(secret, sookie.WithClock(clock), sookie.WithExpiryGrace(30*time.Second))
+	ensure.Nil(t, err)
+	actual = Flash{}
+	ensure.Nil(t, lenient.Open(sealed, &actual))
+	ensure.DeepEqual(t, actual, given)
+	ensure.Nil(t, lenient.Valid(sealed))
+
+	late, err := sookie.New(secret,
+		sookie.WithClock(func() time.Time { return now.Add(2 * time.Minute) }),
+		sookie.WithExpiryGrace(30*time.Second))
+	ensure.Nil(t, err)
+	ensure.True(t, errors.Is(late.Open(sealed, &actual), sookie.ErrExpired))
+}
+
+func TestWithExpiryGraceNegative(t *testing.T) {
+	_, err := sookie.New(secret, sookie.WithExpiryGrace(-time.Second))
+	ensure.StringContains(t, err.Error(), "must not be negative")
+}
+
+func TestWithDefaultExpirySet(t *testing.T) {
+	c, err := sookie.New(secret, sookie.WithDefaultExpiry(time.Hour))
+	ensure.Nil(t, err)
+	w := httptest.NewRecorder()
+	ensure.Nil(t, c.Set(w, given, http.Cookie{Name: cookieName}))
+	set := w.Header().Get("Set-Cookie")
+	ensure.False(t, strings.Contains(set, "Max-Age"))
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Cookie", set)
+	cookie, err := r.Cookie(cookieName)
+	ensure.Nil(t, err)
+	_, expires, err := sookie.OpenRaw(secret, cookie.Value)
+	ensure.Nil(t, err)
+	ensure.True(t, expires.After(time.Now().Add(59*time.Minute)))
+	ensure.True(t, !expires.After(time.Now().Add(time.Hour)))
+}
+
+func TestWithDefaultExpirySeal(t *testing.T) {
+	now := time.Now()
+	c, err := sookie.New(secret, sookie.WithDefaultExpiry(time.Hour),
+		sookie.WithClock(func() time.Time { return now }))
+	ensure.Nil(t, err)
+	sealed, err := c.Seal(time.Time{}, given)
+	ensure.Nil(t, err)
+	_, expires, err := sookie.OpenRaw(secret, sealed)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, expires.Unix(), now.Add(time.Hour).Unix())
+}
+
+func TestWithDefaultExpiryInvalid(t *testing.T) {
+	_, err := sookie.New(secret, sookie.WithDefaultExpiry(0))
+	ensure.StringContains(t, err.Error(), "must be positive")
+}
+
+func TestWithMaxLifetime(t *testing.T) {
+	now := time.Now()
+	sealed, err := sookie.Seal(secret, time.Time{}, given)
+	ensure.Nil(t, err)
+
+	c, err := sookie.New(secret, sookie.WithMaxLifetime(time.Hour),
+		sookie.WithClock(func() time.Time { return now.Add(30 * time.Minute) }))
+	ensure.Nil(t, err)
+	var actual Flash
+	ensure.Nil(t, c.Open(sealed, &actual))
+	ensure.DeepEqual(t, actual, given)
+
+	late, err := sookie.New(secret, sookie.WithMaxLifetime(time.Hour),
+		sookie.WithClock(func() time.Time { return now.Add(2 * time.Hour) }))
+	ensure.Nil(t, err)
+	ensure.True(t, errors.Is(late.Open(sealed, &actual), sookie.ErrExpired))
+}
+
+func TestWithMaxLifetimeExpiryTooFar(t *testing.T) {
+	sealed, err := sookie.Seal(secret, time.Now().Add(48*time.Hour), given)
+	ensure.Nil(t, err)
+	c, err := sookie.New(secret, sookie.WithMaxLifetime(24*time.Hour))
+	ensure.Nil(t, err)
+	ensure.True(t, errors.Is(c.Valid(sealed), sookie.ErrLifetime))
+}
+
+func TestWithMaxLifetimeCreatedInFuture(t *testing.T) {
+	future, err := sookie.New(secret,
+		sookie.WithClock(func() time.Time { return time.Now().Add(time.Hour) }))
+	ensure.Nil(t, err)
+	sealed, err := future.Seal(time.Time{}, given)
+	ensure.Nil(t, err)
+	c, err := sookie.New(secret, sookie.WithMaxLifetime(24*time.Hour))
+	ensure.Nil(t, err)
+	var actual Flash
+	ensure.True(t, errors.Is(c.Open(sealed, &actual), sookie.ErrLifetime))
+}
+
+func TestWithMaxLifetimeInvalid(t *testing.T) {
+	_, err := sookie.New(secret, sookie.WithMaxLifetime(0))
+	ensure.StringContains(t, err.Error(), "at least a second")
+}
+
+func TestWithSecret(t *testing.T) {
+	c, err := sookie.New(secret, sookie.WithMarshaler(sookie.Gob), sookie.WithPadding(32))
+	ensure.Nil(t, err)
+	newSecret := bytes.Repeat([]byte("n"), 32)
+	rotated, err := c.WithSecret(newSecret)
+	ensure.Nil(t, err)
+
+	sealed, err := rotated.Seal(time.Time{}, given)
+	ensure.Nil(t, err)
+	var actual Flash
+	ensure.True(t, errors.Is(c.Open(sealed, &actual), sookie.ErrDecrypt))
+
+	// the options carry over to the copy
+	fresh, err := sookie.New(newSecret, sookie.WithMarshaler(sookie.Gob))
+	ensure.Nil(t, err)
+	ensure.Nil(t, fresh.Open(sealed, &actual))
+	ensure.DeepEqual(t, actual, given)
+	_, err = sookie.Open[Flash](newSecret, sealed)
+	ensure.True(t, errors.Is(err, sookie.ErrFormat))
+}
+
+func TestWithSecretInvalid(t *testing.T) {
+	c, err := sookie.New(secret)
+	ensure.Nil(t, err)
+	_, err = c.WithSecret([]byte("short"))
+	ensure.True(t, errors.Is(err, sookie.ErrAEAD))
+}
+
+func TestWithEncoding(t *testing.T) {
+	c, err := sookie.New(secret, sookie.WithEncoding(base64.URLEncoding))
+	ensure.Nil(t, err)
+	for _, value := range []string{"a", "ab", "abc"} {
+		sealed, err := c.Seal(time.Time{}, value)
+		ensure.Nil(t, err)
+		ensure.DeepEqual(t, len(sealed)%4, 0)
+		var actual string
+		ensure.Nil(t, c.Open(sealed, &actual))
+		ensure.DeepEqual(t, actual, value)
+	}
+}
+
+func TestWithEncodingMismatch(t *testing.T) {
+	c, err := sookie.New(secret, sookie.WithEncoding(base64.URLEncoding))
+	ensure.Nil(t, err)
+	padded := 0
+	for _, value := range []string{"a", "ab", "abc"} {
+		sealed, err := c.Seal(time.Time{}, value)
+		ensure.Nil(t, err)
+		if !strings.HasSuffix(sealed, "=") {
+			continue
+		}
+		padded++
+		_, err = sookie.Open[string](secret, sealed)
+		ensure.True(t, errors.Is(err, sookie.ErrDecode))
+	}
+	ensure.True(t, padded > 0)
+}
+
+func TestCompatibleEncoding(t *testing.T) {
+	c, err := sookie.New(secret, sookie.WithEncoding(sookie.CompatibleEncoding))
+	ensure.Nil(t, err)
+	for range 10 {
+		sealed, err := c.Seal(time.Time{}, given)
+		ensure.Nil(t, err)
+		ensure.False(t, strings.ContainsAny(sealed, "+/=_"))
+		var actual Flash
+		ensure.Nil(t, c.Open(sealed, &actual))
+		ensure.DeepEqual(t, actual, given)
+	}
+}
+
+func TestWithEncodingInvalidAlphabet(t *testing.T) {
+	encoding := base64.NewEncoding(
+		"ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-,")
+	_, err := sookie.New(secret, sookie.WithEncoding(encoding))
+	ensure.NotNil(t, err)
+	ensure.StringContains(t, err.Error(), "invalid character ',' in encoding")
+	_, err = sookie.New(secret, sookie.WithEncoding(base64.URLEncoding.WithPadding(';')))
+	ensure.NotNil(t, err)
+}
+
+func BenchmarkSeal(b *testing.B) {
+	c, err := sookie.New(secret)
+	ensure.Nil(b, err)
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := c.Seal(time.Time{}, given); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkOpen(b *testing.B) {
+	c, err := sookie.New(secret)
+	ensure.Nil(b, err)
+	sealed, err := c.Seal(time.Time{}, given)
+	ensure.Nil(b, err)
+	b.ReportAllocs()
+	for b.Loop() {
+		var actual Flash
+		if err := c.Open(sealed, &actual); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSet(b *testing.B) {
+	c, err := sookie.New(secret)
+	ensure.Nil(b, err)
+	cookie := http.Cookie{Name: cookieName}
+	b.ReportAllocs()
+	for b.Loop() {
+		w := httptest.NewRecorder()
+		if err := c.Set(w, given, cookie); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestRemainingTTL(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	c, err := sookie.New(secret, sookie.WithClock(func() time.Time { return now }))
+	ensure.Nil(t, err)
+	raw, err := c.Seal(now.Add(time.Hour), given)
+	ensure.Nil(t, err)
+	ttl, err := c.RemainingTTL(raw)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, ttl, time.Hour)
+
+	raw, err = c.Seal(time.Time{}, given)
+	ensure.Nil(t, err)
+	ttl, err = c.RemainingTTL(raw)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, ttl, sookie.Permanent)
+
+	raw, err = c.Seal(now.Add(-time.Hour), given)
+	ensure.Nil(t, err)
+	ttl, err = c.RemainingTTL(raw)
+	ensure.True(t, errors.Is(err, sookie.ErrExpired))
+	ensure.DeepEqual(t, ttl, time.Duration(0))
+}
+
+func TestRemainingTTLMaxLifetime(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	c, err := sookie.New(secret,
+		sookie.WithClock(func() time.Time { return now }),
+		sookie.WithMaxLifetime(time.Hour))
+	ensure.Nil(t, err)
+	raw, err := c.Seal(time.Time{}, given)
+	ensure.Nil(t, err)
+	ttl, err := c.RemainingTTL(raw)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, ttl, time.Hour)
+}
+
+func TestWithNotBefore(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	clock := func() time.Time { return now }
+	sealer, err := sookie.New(secret, sookie.WithClock(clock))
+	ensure.Nil(t, err)
+	old, err := sealer.Seal(time.Time{}, given)
+	ensure.Nil(t, err)
+
+	now = now.Add(time.Hour)
+	fresh, err := sealer.Seal(time.Time{}, given)
+	ensure.Nil(t, err)
+
+	c, err := sookie.New(secret, sookie.WithClock(clock), sookie.WithNotBefore(now.Add(-time.Minute)))
+	ensure.Nil(t, err)
+	var actual Flash
+	ensure.True(t, errors.Is(c.Open(old, &actual), sookie.ErrStale))
+	ensure.True(t, errors.Is(c.Valid(old), sookie.ErrStale))
+	ensure.Nil(t, c.Open(fresh, &actual))
+	ensure.DeepEqual(t, actual, given)
+}
+
+func TestWithIdleTimeout(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	clock := func() time.Time { return now }
+	c, err := sookie.New(secret, sookie.WithClock(clock), sookie.WithIdleTimeout(time.Hour))
+	ensure.Nil(t, err)
+	raw, err := c.Seal(time.Time{}, given)
+	ensure.Nil(t, err)
+
+	now = now.Add(30 * time.Minute)
+	ttl, err := c.RemainingTTL(raw)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, ttl, 30*time.Minute)
+	resealed, err := c.Seal(time.Time{}, given)
+	ensure.Nil(t, err)
+
+	now = now.Add(45 * time.Minute)
+	var actual Flash
+	ensure.True(t, errors.Is(c.Open(raw, &actual), sookie.ErrExpired))
+	ensure.Nil(t, c.Open(resealed, &actual))
+	ensure.DeepEqual(t, actual, given)
+
+	// the idle timeout is stored in the value
+	plain, err := sookie.New(secret, sookie.WithClock(clock))
+	ensure.Nil(t, err)
+	ensure.True(t, errors.Is(plain.Open(raw, &actual), sookie.ErrExpired))
+}
+
+func TestWithIdleTimeoutInvalid(t *testing.T) {
+	_, err := sookie.New(secret, sookie.WithIdleTimeout(time.Millisecond))
+	ensure.NotNil(t, err)
+}
+
+func TestWithValuePrefix(t *testing.T) {
+	c, err := sookie.New(secret, sookie.WithValuePrefix("v1."))
+	ensure.Nil(t, err)
+	raw, err := c.Seal(time.Time{}, given)
+	ensure.Nil(t, err)
+	ensure.True(t, strings.HasPrefix(raw, "v1."))
+	var actual Flash
+	ensure.Nil(t, c.Open(raw, &actual))
+	ensure.DeepEqual(t, actual, given)
+
+	unprefixed, err := sookie.Seal(secret, time.Time{}, given)
+	ensure.Nil(t, err)
+	ensure.True(t, errors.Is(c.Open(unprefixed, &actual), sookie.ErrFormat))
+	ensure.True(t, errors.Is(c.Open("v2."+strings.TrimPrefix(raw, "v1."), &actual), sookie.ErrFormat))
+
+	w := httptest.NewRecorder()
+	ensure.Nil(t, c.Set(w, given, http.Cookie{Name: cookieName}))
+	ensure.StringContains(t, w.Header().Get("Set-Cookie"), cookieName+"=v1.")
+}
+
+func TestWithValuePrefixInvalid(t *testing.T) {
+	_, err := sookie.New(secret, sookie.WithValuePrefix(""))
+	ensure.NotNil(t, err)
+	_, err = sookie.New(secret, sookie.WithValuePrefix("v 1;"))
+	ensure.NotNil(t, err)
+}
+
+func TestOpenWithMetadataIdleTimeout(t *testing.T) {
+	c, err := sookie.New(secret, sookie.WithIdleTimeout(time.Hour))
+	ensure.Nil(t, err)
+	raw, err := c.Seal(time.Time{}, given)
+	ensure.Nil(t, err)
+	actual, md, err := sookie.OpenWithMetadata[Flash](secret, raw)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, actual, given)
+	ensure.DeepEqual(t, md.IdleTimeout, time.Hour)
+	ensure.True(t, md.Expiry.IsZero())
+	ensure.DeepEqual(t, md.Label, "")
+}
+
+func TestWithPreciseExpiry(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	clock := func() time.Time { return now }
+	c, err := sookie.New(secret, sookie.WithClock(clock), sookie.WithPreciseExpiry(true))
+	ensure.Nil(t, err)
+	raw, err := c.Seal(now.Add(300*time.Millisecond), given)
+	ensure.Nil(t, err)
+
+	now = now.Add(200 * time.Millisecond)
+	var actual Flash
+	ensure.Nil(t, c.Open(raw, &actual))
+	md, err := c.OpenWithMetadata(raw, &actual)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, md.Expiry.UnixMilli(), now.Add(100*time.Millisecond).UnixMilli())
+
+	now = now.Add(200 * time.Millisecond)
+	ensure.True(t, errors.Is(c.Open(raw, &actual), sookie.ErrExpired))
+}
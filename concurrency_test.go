@@ -0,0 +1,73 @@
+package sookie_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/daaku/ensure"
+	"github.com/daaku/sookie"
+)
+
+// hammer seals and opens distinct values from many goroutines at once, which
+// together with the race detector checks that sharing a Codec is safe.
+func hammer(t *testing.T, seal func(Flash) (string, error), open func(string) (Flash, error)) {
+	const goroutines, iterations = 16, 50
+	var wg sync.WaitGroup
+	errs := make(chan error, goroutines)
+	for g := range goroutines {
+		wg.Go(func() {
+			for i := range iterations {
+				value := Flash{Kind: fmt.Sprint(g), Content: fmt.Sprintf("%d-%d", g, i)}
+				raw, err := seal(value)
+				if err != nil {
+					errs <- err
+					return
+				}
+				actual, err := open(raw)
+				if err != nil {
+					errs <- err
+					return
+				}
+				if actual != value {
+					errs <- fmt.Errorf("opened %v, sealed %v", actual, value)
+					return
+				}
+			}
+		})
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+}
+
+func TestConcurrentPackageFunctions(t *testing.T) {
+	hammer(t,
+		func(v Flash) (string, error) { return sookie.Seal(secret, time.Time{}, v) },
+		func(raw string) (Flash, error) { return sookie.Open[Flash](secret, raw) })
+}
+
+func TestConcurrentCodec(t *testing.T) {
+	for name, options := range map[string][]sookie.Option{
+		"default": nil,
+		"gzip":    {sookie.WithCompressor(sookie.Gzip)},
+		"dict":    {sookie.WithCompressionDict([]byte("alert-success alert-danger"))},
+		"signed":  {sookie.WithSignOnly(true)},
+		"padded":  {sookie.WithPadding(64), sookie.WithOuterMAC(secret)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c, err := sookie.New(secret, options...)
+			ensure.Nil(t, err)
+			hammer(t,
+				func(v Flash) (string, error) { return c.Seal(time.Time{}, v) },
+				func(raw string) (Flash, error) {
+					var actual Flash
+					err := c.Open(raw, &actual)
+					return actual, err
+				})
+		})
+	}
+}
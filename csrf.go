@@ -0,0 +1,136 @@
+package sookie
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// csrfToken is the value sealed in a CSRF token.
+type csrfToken struct {
+	// R is random, so every token is unique.
+	R []byte
+	// S is the session the token is bound to, if any.
+	S string
+}
+
+// csrfContextKey is the context key for the CSRF token of the request.
+type csrfContextKey struct{}
+
+// CSRF issues and validates tokens for the double submit cookie pattern. The
+// token is sealed into a cookie by the Middleware, and the same token must be
+// submitted with requests that change state, such as in a form field, where
+// it is checked by Validate. Tokens expire, and may be bound to a session so
+// a token issued for one session is rejected for another.
+// A CSRF is safe for concurrent use by multiple goroutines.
+type CSRF struct {
+	codec   *Codec
+	cookie  http.Cookie
+	ttl     time.Duration
+	session func(r *http.Request) string
+}
+
+// NewCSRF creates a CSRF storing tokens in the cookie named by the template,
+// which expire after ttl. If session is not nil, it returns the session ID for
+// a request, and tokens are bound to it. The template should not set MaxAge or
+// Expires, as those are set from ttl. Tokens are sealed using a Codec created
+// with the given secret and options.
+func NewCSRF(secret []byte, cookie http.Cookie, ttl time.Duration, session func(r *http.Request) string, options ...Option) (*CSRF, error) {
+	if ttl < time.Second {
+		return nil, errors.New("sookie: CSRF token ttl must be at least a second")
+	}
+	if cookie.Value != "" {
+		return nil, ErrValueMustBeEmpty
+	}
+	if err := checkPrefix(&cookie); err != nil {
+		return nil, err
+	}
+	c, err := New(secret, options...)
+	if err != nil {
+		return nil, err
+	}
+	return &CSRF{codec: c.forName(cookie.Name), cookie: cookie, ttl: ttl, session: session}, nil
+}
+
+// sessionID returns the session ID for the request, if tokens are bound to one.
+func (c *CSRF) sessionID(r *http.Request) string {
+	if c.session == nil {
+		return ""
+	}
+	return c.session(r)
+}
+
+// check opens the token and checks it is bound to the session of the request.
+func (c *CSRF) check(r *http.Request, raw string) error {
+	var t csrfToken
+	if err := c.codec.Open(raw, &t); err != nil {
+		return fmt.Errorf("%w: %w", ErrCSRF, err)
+	}
+	if !SecureEqual(t.S, c.sessionID(r)) {
+		return fmt.Errorf("%w: bound to a different session", ErrCSRF)
+	}
+	return nil
+}
+
+// Middleware ensures the request has a valid CSRF token, setting a new token
+// cookie on the response if it does not. The token is available to the
+// wrapped handler via CSRFToken, to be included in forms.
+func (c *CSRF) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := ""
+		if existing, err := r.Cookie(c.cookie.Name); err == nil && c.check(r, existing.Value) == nil {
+			token = existing.Value
+		} else {
+			issued, err := c.issue(w, r)
+			if err != nil {
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+			token = issued
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), csrfContextKey{}, token)))
+	})
+}
+
+// issue seals a new token and sets it on the response, returning it.
+func (c *CSRF) issue(w http.ResponseWriter, r *http.Request) (string, error) {
+	t := csrfToken{R: make([]byte, 32), S: c.sessionID(r)}
+	if _, err := rand.Read(t.R); err != nil {
+		return "", fmt.Errorf("sookie: failed to read CSRF token: %w", err)
+	}
+	expires := c.codec.now().Add(c.ttl)
+	raw, err := c.codec.Seal(expires, t)
+	if err != nil {
+		return "", err
+	}
+	cookie := c.cookie
+	cookie.Value = raw
+	cookie.MaxAge = MaxAge(c.ttl)
+	cookie.Expires = time.Time{}
+	setCookie(w, &cookie)
+	return raw, nil
+}
+
+// Validate checks the submitted token matches the token cookie of the
+// request, that it has not expired, and that it is bound to the session of the
+// request. It returns nil if the token is valid, or the ErrCSRF error.
+func (c *CSRF) Validate(r *http.Request, submitted string) error {
+	existing, err := r.Cookie(c.cookie.Name)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrCSRF, err)
+	}
+	if submitted == "" || !SecureEqual(existing.Value, submitted) {
+		return fmt.Errorf("%w: token mismatch", ErrCSRF)
+	}
+	return c.check(r, existing.Value)
+}
+
+// CSRFToken returns the CSRF token for the request, made available by the
+// CSRF Middleware. It returns an empty string outside the Middleware.
+func CSRFToken(r *http.Request) string {
+	token, _ := r.Context().Value(csrfContextKey{}).(string)
+	return token
+}
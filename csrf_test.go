@@ -0,0 +1,94 @@
+package sookie_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daaku/ensure"
+	"github.com/daaku/sookie"
+)
+
+func serveCSRF(t *testing.T, csrf *sookie.CSRF, r *http.Request) (*httptest.ResponseRecorder, string) {
+	var token string
+	handler := csrf.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token = sookie.CSRFToken(r)
+	}))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w, token
+}
+
+func TestCSRF(t *testing.T) {
+	csrf, err := sookie.NewCSRF(secret, http.Cookie{Name: "csrf", Path: "/"}, time.Hour, nil)
+	ensure.Nil(t, err)
+	w, token := serveCSRF(t, csrf, httptest.NewRequest("GET", "/", nil))
+	ensure.True(t, token != "")
+	ensure.StringContains(t, w.Header().Get("Set-Cookie"), "Max-Age=3600")
+
+	r := httptest.NewRequest("POST", "/", nil)
+	r.Header.Set("Cookie", w.Header().Get("Set-Cookie"))
+	ensure.Nil(t, csrf.Validate(r, token))
+	ensure.True(t, errors.Is(csrf.Validate(r, ""), sookie.ErrCSRF))
+	ensure.True(t, errors.Is(csrf.Validate(r, token+"x"), sookie.ErrCSRF))
+
+	// an existing valid token is reused
+	w, again := serveCSRF(t, csrf, r)
+	ensure.DeepEqual(t, again, token)
+	ensure.DeepEqual(t, w.Header().Get("Set-Cookie"), "")
+}
+
+func TestCSRFOptions(t *testing.T) {
+	csrf, err := sookie.NewCSRF(secret, http.Cookie{Name: "csrf", Path: "/"}, time.Hour, nil,
+		sookie.WithBindName(true), sookie.WithValuePrefix("csrf."))
+	ensure.Nil(t, err)
+	w, token := serveCSRF(t, csrf, httptest.NewRequest("GET", "/", nil))
+	ensure.True(t, strings.HasPrefix(token, "csrf."))
+	r := httptest.NewRequest("POST", "/", nil)
+	r.Header.Set("Cookie", w.Header().Get("Set-Cookie"))
+	ensure.Nil(t, csrf.Validate(r, token))
+
+	// the token is bound to the cookie name
+	other, err := sookie.NewCSRF(secret, http.Cookie{Name: "other", Path: "/"}, time.Hour, nil,
+		sookie.WithBindName(true), sookie.WithValuePrefix("csrf."))
+	ensure.Nil(t, err)
+	r = httptest.NewRequest("POST", "/", nil)
+	r.AddCookie(&http.Cookie{Name: "other", Value: token})
+	ensure.True(t, errors.Is(other.Validate(r, token), sookie.ErrDecrypt))
+}
+
+func TestCSRFMissingCookie(t *testing.T) {
+	csrf, err := sookie.NewCSRF(secret, http.Cookie{Name: "csrf"}, time.Hour, nil)
+	ensure.Nil(t, err)
+	r := httptest.NewRequest("POST", "/", nil)
+	ensure.True(t, errors.Is(csrf.Validate(r, "token"), sookie.ErrCSRF))
+}
+
+func TestCSRFSessionBound(t *testing.T) {
+	session := "alice"
+	csrf, err := sookie.NewCSRF(secret, http.Cookie{Name: "csrf"}, time.Hour,
+		func(r *http.Request) string { return session })
+	ensure.Nil(t, err)
+	w, token := serveCSRF(t, csrf, httptest.NewRequest("GET", "/", nil))
+	r := httptest.NewRequest("POST", "/", nil)
+	r.Header.Set("Cookie", w.Header().Get("Set-Cookie"))
+	ensure.Nil(t, csrf.Validate(r, token))
+
+	session = "mallory"
+	ensure.True(t, errors.Is(csrf.Validate(r, token), sookie.ErrCSRF))
+	_, reissued := serveCSRF(t, csrf, r)
+	ensure.False(t, reissued == token)
+}
+
+func TestCSRFExpired(t *testing.T) {
+	csrf, err := sookie.NewCSRF(secret, http.Cookie{Name: "csrf"}, time.Hour, nil)
+	ensure.Nil(t, err)
+	raw, err := sookie.Seal(secret, time.Now().Add(-time.Minute), struct{ R []byte }{R: []byte("x")})
+	ensure.Nil(t, err)
+	r := httptest.NewRequest("POST", "/", nil)
+	r.AddCookie(&http.Cookie{Name: "csrf", Value: raw})
+	ensure.True(t, errors.Is(csrf.Validate(r, raw), sookie.ErrExpired))
+}
@@ -0,0 +1,29 @@
+package sookie
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// WithDebugPlaintext configures the Codec to store values as unencrypted
+// JSON, so they can be read in the browser developer tools during local
+// development. The value is the base64 encoding of a two byte header followed
+// by the JSON of the wrapper, see the readme. This replaces the Marshaler.
+//
+// Values are neither encrypted nor authenticated, so this refuses to be
+// enabled unless the program is built with the sookie_debug build tag. The
+// header marks debug values, and a Codec without this option always rejects
+// them with the ErrFormat error, so they are never trusted in production.
+func WithDebugPlaintext(enabled bool) Option {
+	return func(c *Codec) error {
+		if !enabled {
+			return nil
+		}
+		if !debugPlaintextAllowed {
+			return errors.New("sookie: debug plaintext requires the sookie_debug build tag")
+		}
+		c.debug = true
+		c.marshaler = funcMarshaler{id: jsonID, marshal: json.Marshal, unmarshal: json.Unmarshal}
+		return nil
+	}
+}
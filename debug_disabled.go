@@ -0,0 +1,6 @@
+//go:build !sookie_debug
+
+package sookie
+
+// debugPlaintextAllowed reports if WithDebugPlaintext may be enabled.
+const debugPlaintextAllowed = false
@@ -0,0 +1,37 @@
+//go:build sookie_debug
+
+package sookie_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/daaku/ensure"
+	"github.com/daaku/sookie"
+)
+
+func TestWithDebugPlaintext(t *testing.T) {
+	c, err := sookie.New(secret, sookie.WithDebugPlaintext(true))
+	ensure.Nil(t, err)
+	sealed, err := c.Seal(time.Time{}, given)
+	ensure.Nil(t, err)
+
+	message, err := base64.RawURLEncoding.DecodeString(sealed)
+	ensure.Nil(t, err)
+	var w struct {
+		V Flash
+		E int64
+	}
+	ensure.Nil(t, json.Unmarshal(message[2:], &w))
+	ensure.DeepEqual(t, w.V, given)
+
+	var actual Flash
+	ensure.Nil(t, c.Open(sealed, &actual))
+	ensure.DeepEqual(t, actual, given)
+
+	_, err = sookie.Open[Flash](secret, sealed)
+	ensure.True(t, errors.Is(err, sookie.ErrFormat))
+}
@@ -0,0 +1,36 @@
+package sookie_test
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/daaku/ensure"
+	"github.com/daaku/sookie"
+)
+
+func TestWithDebugPlaintextRequiresBuildTag(t *testing.T) {
+	_, err := sookie.New(secret, sookie.WithDebugPlaintext(true))
+	if err == nil {
+		t.Skip("built with the sookie_debug build tag")
+	}
+	ensure.StringContains(t, err.Error(), "sookie_debug build tag")
+}
+
+func TestWithDebugPlaintextDisabled(t *testing.T) {
+	c, err := sookie.New(secret, sookie.WithDebugPlaintext(false))
+	ensure.Nil(t, err)
+	sealed, err := c.Seal(time.Time{}, given)
+	ensure.Nil(t, err)
+	actual, err := sookie.Open[Flash](secret, sealed)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, actual, given)
+}
+
+func TestOpenRejectsDebugValue(t *testing.T) {
+	raw := base64.RawURLEncoding.EncodeToString(
+		append([]byte{0xff, 3}, `{"V":{"Kind":"success","Message":"Hello world!"},"E":-1}`...))
+	_, err := sookie.Open[Flash](secret, raw)
+	ensure.True(t, errors.Is(err, sookie.ErrFormat))
+}
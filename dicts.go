@@ -0,0 +1,114 @@
+package sookie
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// WithCompressionDicts configures several raw Zstandard dictionaries, keyed by
+// a short ID, so the dictionary can be changed without invalidating values
+// compressed using an earlier one, much like rotating secrets. Values are
+// compressed using the dictionary with the current ID, which is stored in
+// cleartext in the header, and are decompressed using the dictionary with the
+// ID in their header. Values compressed using a dictionary which is no longer
+// configured fail to open with the ErrFormat error, and ResealDict compresses
+// values using the current dictionary, such as when they are read. This can
+// not be combined with WithCompressionDict.
+func WithCompressionDicts(current byte, dicts map[byte][]byte) Option {
+	return func(c *Codec) error {
+		if _, ok := dicts[current]; !ok {
+			return fmt.Errorf("sookie: no compression dictionary with the current ID %d", current)
+		}
+		for id, dict := range dicts {
+			if len(dict) == 0 {
+				return fmt.Errorf("sookie: compression dictionary %d must not be empty", id)
+			}
+		}
+		c.dictVersion = current
+		c.dictSources = dicts
+		return nil
+	}
+}
+
+// newDictDecoders creates the decoders for the dictionaries configured by
+// WithCompressionDicts, and the encoder for the current one.
+func (c *Codec) newDictDecoders() error {
+	if len(c.dict) != 0 {
+		return errors.New("sookie: WithCompressionDict can not be combined with WithCompressionDicts")
+	}
+	if c.compressor != Zstd {
+		return errors.New("sookie: compression dictionaries are only supported with Zstd")
+	}
+	c.dicts = make(map[byte]*zstd.Decoder, len(c.dictSources))
+	for id, dict := range c.dictSources {
+		d, err := newDecoder(c.maxDecompressedSize, dict)
+		if err != nil {
+			return fmt.Errorf("sookie: failed to create decoder: %w", err)
+		}
+		c.dicts[id] = d
+	}
+	dict := c.dictSources[c.dictVersion]
+	e, err := zstd.NewWriter(nil, zstd.WithEncoderDictRaw(dictID(dict), dict))
+	if err != nil {
+		return fmt.Errorf("sookie: failed to create encoder: %w", err)
+	}
+	c.encoder = e
+	return nil
+}
+
+// decompressDict decompresses a frame compressed using the dictionary with
+// the given ID.
+func (c *Codec) decompressDict(id byte, plaintext []byte) ([]byte, error) {
+	d, ok := c.dicts[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown compression dictionary %d", ErrFormat, id)
+	}
+	return decodeAll(d, plaintext)
+}
+
+// ResealDict seals the raw value again if it was compressed using a
+// dictionary other than the current one, see WithCompressionDicts, returning
+// the new value and true. The value and its expiry are preserved exactly, as
+// with Rewrap. Values already using the current dictionary, or compressed
+// without one, are returned as is along with false. If the raw value is
+// expired, the ErrExpired error is returned.
+func (c *Codec) ResealDict(raw string) (string, bool, error) {
+	stale, err := c.staleDict(raw)
+	if err != nil || !stale {
+		return raw, false, err
+	}
+	resealed, err := c.Rewrap(c, raw)
+	if err != nil {
+		return raw, false, err
+	}
+	return resealed, true, nil
+}
+
+// staleDict reports if the raw value was compressed using a dictionary other
+// than the current one.
+func (c *Codec) staleDict(raw string) (bool, error) {
+	if c.dicts == nil {
+		return false, nil
+	}
+	raw, err := c.cutPrefix(raw)
+	if err != nil {
+		return false, err
+	}
+	message, err := c.encoding.DecodeString(raw)
+	if err != nil {
+		return false, decodeError(raw, err)
+	}
+	if len(message) < minMessageSize {
+		return false, errTooShort
+	}
+	if message[0] == formatSigned {
+		return message[2] == compressionZstdDictID && message[headerSize] != c.dictVersion, nil
+	}
+	h, _, _, err := parseHeader(message)
+	if err != nil {
+		return false, err
+	}
+	return h.compression == compressionZstdDictID && h.dictID != c.dictVersion, nil
+}
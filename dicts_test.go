@@ -0,0 +1,56 @@
+package sookie_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daaku/ensure"
+	"github.com/daaku/sookie"
+)
+
+var (
+	dictV1 = []byte(strings.Repeat("alert-success alert-danger Kind Content ", 4))
+	dictV2 = []byte(strings.Repeat("alert-info alert-warning Kind Content ", 4))
+	large  = Flash{Kind: "alert-success", Content: strings.Repeat("compressible ", 20)}
+)
+
+func TestWithCompressionDictsRotation(t *testing.T) {
+	old, err := sookie.New(secret, sookie.WithCompressionDicts(1, map[byte][]byte{1: dictV1}))
+	ensure.Nil(t, err)
+	raw, err := old.Seal(time.Time{}, large)
+	ensure.Nil(t, err)
+
+	c, err := sookie.New(secret, sookie.WithCompressionDicts(2, map[byte][]byte{1: dictV1, 2: dictV2}))
+	ensure.Nil(t, err)
+	var actual Flash
+	ensure.Nil(t, c.Open(raw, &actual))
+	ensure.DeepEqual(t, actual, large)
+
+	resealed, changed, err := c.ResealDict(raw)
+	ensure.Nil(t, err)
+	ensure.True(t, changed)
+	ensure.Nil(t, c.Open(resealed, &actual))
+	ensure.DeepEqual(t, actual, large)
+	_, changed, err = c.ResealDict(resealed)
+	ensure.Nil(t, err)
+	ensure.False(t, changed)
+
+	// once the old dictionary is removed, only resealed values open
+	current, err := sookie.New(secret, sookie.WithCompressionDicts(2, map[byte][]byte{2: dictV2}))
+	ensure.Nil(t, err)
+	ensure.True(t, errors.Is(current.Open(raw, &actual), sookie.ErrFormat))
+	ensure.Nil(t, current.Open(resealed, &actual))
+}
+
+func TestWithCompressionDictsInvalid(t *testing.T) {
+	_, err := sookie.New(secret, sookie.WithCompressionDicts(2, map[byte][]byte{1: dictV1}))
+	ensure.NotNil(t, err)
+	_, err = sookie.New(secret, sookie.WithCompressionDicts(1, map[byte][]byte{1: nil}))
+	ensure.NotNil(t, err)
+	_, err = sookie.New(secret,
+		sookie.WithCompressionDicts(1, map[byte][]byte{1: dictV1}),
+		sookie.WithCompressionDict(dictV2))
+	ensure.NotNil(t, err)
+}
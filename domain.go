@@ -0,0 +1,82 @@
+package sookie
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// SetForDomain sets a cookie like Set, shared by the domain and all of its
+// subdomains, such as "example.com" for a cookie read by both
+// "www.example.com" and "api.example.com". A leading dot on the domain is
+// accepted and removed, as browsers ignore it. The domain must be a host name
+// with at least two labels, not an IP address, a port or a URL, since
+// browsers silently reject such cookies or limit them to the exact host.
+// Domains which are public suffixes, such as "co.uk", are not detected, and
+// are rejected by browsers.
+//
+// The cookie is sent to every subdomain, so it is always Secure, and its Path
+// defaults to "/" and its SameSite to Lax, so it is available on every page.
+// The Name and Domain of the template are replaced, and __Host- cookies can
+// not be used as they may not have a Domain.
+func SetForDomain[V any](secret []byte, w http.ResponseWriter, value V, name, domain string, cookie http.Cookie) error {
+	c, err := New(secret)
+	if err != nil {
+		return err
+	}
+	return c.SetForDomain(w, value, name, domain, cookie)
+}
+
+// SetForDomain sets a cookie shared by the domain and its subdomains like the
+// package level SetForDomain function.
+func (c *Codec) SetForDomain(w http.ResponseWriter, value any, name, domain string, cookie http.Cookie) error {
+	domain, err := cookieDomain(domain)
+	if err != nil {
+		return err
+	}
+	cookie.Name = name
+	cookie.Domain = domain
+	cookie.Secure = true
+	if cookie.Path == "" {
+		cookie.Path = "/"
+	}
+	if cookie.SameSite == 0 || cookie.SameSite == http.SameSiteDefaultMode {
+		cookie.SameSite = http.SameSiteLaxMode
+	}
+	return c.Set(w, value, cookie)
+}
+
+// cookieDomain validates and normalizes a domain shared with subdomains.
+func cookieDomain(domain string) (string, error) {
+	d := strings.ToLower(strings.TrimPrefix(domain, "."))
+	if net.ParseIP(d) != nil {
+		return "", fmt.Errorf("%w: domain %q is an IP address", ErrInvalidCookie, domain)
+	}
+	labels := strings.Split(d, ".")
+	if len(labels) < 2 {
+		return "", fmt.Errorf("%w: domain %q must have at least two labels", ErrInvalidCookie, domain)
+	}
+	for _, label := range labels {
+		if !validDomainLabel(label) {
+			return "", fmt.Errorf("%w: invalid domain %q", ErrInvalidCookie, domain)
+		}
+	}
+	return d, nil
+}
+
+// validDomainLabel reports if the label is a valid host name label, of ASCII
+// letters, digits and '-', not starting or ending with '-'.
+func validDomainLabel(label string) bool {
+	if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+		return false
+	}
+	for _, r := range label {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,46 @@
+package sookie_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/daaku/ensure"
+	"github.com/daaku/sookie"
+)
+
+func TestSetForDomain(t *testing.T) {
+	w := httptest.NewRecorder()
+	err := sookie.SetForDomain(secret, w, given, cookieName, ".Example.com", http.Cookie{HttpOnly: true})
+	ensure.Nil(t, err)
+	cookies := w.Result().Cookies()
+	ensure.DeepEqual(t, len(cookies), 1)
+	cookie := cookies[0]
+	ensure.DeepEqual(t, cookie.Name, cookieName)
+	ensure.DeepEqual(t, cookie.Domain, "example.com")
+	ensure.DeepEqual(t, cookie.Path, "/")
+	ensure.DeepEqual(t, cookie.SameSite, http.SameSiteLaxMode)
+	ensure.True(t, cookie.Secure)
+	ensure.True(t, cookie.HttpOnly)
+	actual, err := sookie.Open[Flash](secret, cookie.Value)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, actual, given)
+}
+
+func TestSetForDomainInvalid(t *testing.T) {
+	for _, domain := range []string{
+		"", "localhost", "127.0.0.1", "::1", "example.com:8080",
+		"https://example.com", "example..com", "-example.com", "exa mple.com",
+	} {
+		w := httptest.NewRecorder()
+		err := sookie.SetForDomain(secret, w, given, cookieName, domain, http.Cookie{})
+		ensure.True(t, errors.Is(err, sookie.ErrInvalidCookie), domain)
+	}
+}
+
+func TestSetForDomainHostPrefix(t *testing.T) {
+	w := httptest.NewRecorder()
+	err := sookie.SetForDomain(secret, w, given, "__Host-flash", "example.com", http.Cookie{})
+	ensure.True(t, errors.Is(err, sookie.ErrInvalidCookie))
+}
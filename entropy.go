@@ -0,0 +1,92 @@
+package sookie
+
+import "fmt"
+
+// minDistinctBytes is the fewest distinct bytes a secret may have before it is
+// considered weak. A random 32 byte secret almost always has more than 25, and
+// even one written as hex has close to 16.
+const minDistinctBytes = 8
+
+// maxWeakPeriod is the longest repeating pattern considered weak, such as
+// "abcabc..." or "secretsecret...".
+const maxWeakPeriod = 8
+
+// CheckSecretEntropy checks the secret for patterns showing it was not
+// randomly generated, such as all zeros, a repeated byte or pattern, or a
+// sequence like "abcdef...", which typically means a placeholder secret was
+// left in the configuration. It returns nil if no such pattern is found, or
+// the ErrWeakSecret error describing it. This is a heuristic, and passing it
+// does not mean the secret is strong, only that it is not obviously weak.
+// Programs can call it at startup to log a warning, or use
+// WithRejectWeakSecret to refuse weak secrets.
+func CheckSecretEntropy(secret []byte) error {
+	if len(secret) == 0 {
+		return fmt.Errorf("%w: empty", ErrWeakSecret)
+	}
+	var seen [256]bool
+	distinct := 0
+	for _, b := range secret {
+		if !seen[b] {
+			seen[b] = true
+			distinct++
+		}
+	}
+	if distinct == 1 {
+		return fmt.Errorf("%w: a single repeated byte", ErrWeakSecret)
+	}
+	if distinct < min(minDistinctBytes, len(secret)) {
+		return fmt.Errorf("%w: only %d distinct bytes", ErrWeakSecret, distinct)
+	}
+	for period := 2; period <= maxWeakPeriod && period < len(secret); period++ {
+		if repeats(secret, period) {
+			return fmt.Errorf("%w: a repeated %d byte pattern", ErrWeakSecret, period)
+		}
+	}
+	if sequential(secret) {
+		return fmt.Errorf("%w: a sequence", ErrWeakSecret)
+	}
+	return nil
+}
+
+// repeats reports if the secret is made of a pattern of the given length.
+func repeats(secret []byte, period int) bool {
+	for i := period; i < len(secret); i++ {
+		if secret[i] != secret[i-period] {
+			return false
+		}
+	}
+	return true
+}
+
+// sequential reports if each byte of the secret differs from the previous one
+// by the same amount, such as "abcdef..." or "9876...".
+func sequential(secret []byte) bool {
+	if len(secret) < 2 {
+		return false
+	}
+	step := secret[1] - secret[0]
+	for i := 2; i < len(secret); i++ {
+		if secret[i]-secret[i-1] != step {
+			return false
+		}
+	}
+	return true
+}
+
+// WithRejectWeakSecret configures whether New and WithSecret fail with the
+// ErrWeakSecret error for secrets which CheckSecretEntropy finds obviously
+// weak, so a placeholder secret never reaches production.
+func WithRejectWeakSecret(reject bool) Option {
+	return func(c *Codec) error {
+		c.rejectWeakSecret = reject
+		return nil
+	}
+}
+
+// checkSecret checks the secret if the options require it.
+func (c *Codec) checkSecret(secret []byte) error {
+	if !c.rejectWeakSecret {
+		return nil
+	}
+	return CheckSecretEntropy(secret)
+}
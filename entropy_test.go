@@ -0,0 +1,35 @@
+package sookie_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/daaku/ensure"
+	"github.com/daaku/sookie"
+)
+
+func TestCheckSecretEntropy(t *testing.T) {
+	ensure.Nil(t, sookie.CheckSecretEntropy(secret))
+	weak := map[string][]byte{
+		"zeros":    make([]byte, 32),
+		"repeated": bytes.Repeat([]byte("a"), 32),
+		"pattern":  bytes.Repeat([]byte("secret!!"), 4),
+		"sequence": []byte("abcdefghijklmnopqrstuvwxyz"),
+		"few":      []byte("aabbccddaabbccddaabbccddaabbccdd"),
+	}
+	for name, s := range weak {
+		t.Run(name, func(t *testing.T) {
+			ensure.True(t, errors.Is(sookie.CheckSecretEntropy(s), sookie.ErrWeakSecret))
+		})
+	}
+}
+
+func TestWithRejectWeakSecret(t *testing.T) {
+	_, err := sookie.New(bytes.Repeat([]byte("a"), 32), sookie.WithRejectWeakSecret(true))
+	ensure.True(t, errors.Is(err, sookie.ErrWeakSecret))
+	c, err := sookie.New(secret, sookie.WithRejectWeakSecret(true))
+	ensure.Nil(t, err)
+	_, err = c.WithSecret(make([]byte, 32))
+	ensure.True(t, errors.Is(err, sookie.ErrWeakSecret))
+}
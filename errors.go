@@ -0,0 +1,118 @@
+package sookie
+
+import "errors"
+
+// Errors returned by sookie. Errors from the underlying encoding, compression
+// or cryptography packages are wrapped, so use errors.Is to match them.
+var (
+	// ErrExpired is returned when the cookie has expired.
+	ErrExpired = errors.New("sookie: cookie expired")
+
+	// ErrDecrypt is returned, wrapped, when the cookie fails authentication.
+	// This happens when it was sealed with a different secret, but also when
+	// it was tampered with or truncated. The AEAD cannot tell these apart.
+	ErrDecrypt = errors.New("sookie: failed to decrypt cookie")
+
+	// ErrValueMustBeEmpty is returned by Set when the cookie template has a Value.
+	ErrValueMustBeEmpty = errors.New("sookie: cookie value must be empty")
+
+	// ErrInvalidLength is returned, possibly wrapped, when the decoded cookie
+	// is too short to contain the format header, nonce and authentication tag.
+	ErrInvalidLength = errors.New("sookie: invalid cookie length")
+
+	// ErrDecode is returned, wrapped, when the cookie is not valid base64.
+	ErrDecode = errors.New("sookie: failed to decode cookie")
+
+	// ErrAEAD is returned, wrapped, when the AEAD cannot be created from the
+	// secret, usually because it is not 32 bytes long.
+	ErrAEAD = errors.New("sookie: failed to create AEAD")
+
+	// ErrMarshal is returned, wrapped, when the value cannot be marshaled.
+	ErrMarshal = errors.New("sookie: failed to marshal value")
+
+	// ErrUnmarshal is returned, wrapped, when the cookie cannot be unmarshaled
+	// into the requested type.
+	ErrUnmarshal = errors.New("sookie: failed to unmarshal cookie")
+
+	// ErrDecompress is returned, wrapped, when the decrypted cookie cannot be
+	// decompressed.
+	ErrDecompress = errors.New("sookie: failed to decompress cookie")
+
+	// ErrDecompressedSize is returned, wrapped, when the decrypted cookie
+	// decompresses to more than the configured maximum size.
+	ErrDecompressedSize = errors.New("sookie: decompressed cookie too large")
+
+	// ErrFormat is returned, wrapped, when the cookie was sealed using a format
+	// or options this Codec does not support, such as a different Marshaler.
+	ErrFormat = errors.New("sookie: unsupported cookie format")
+
+	// ErrLifetime is returned, wrapped, when the creation time or expiry of the
+	// cookie is implausible given the maximum lifetime, see WithMaxLifetime.
+	ErrLifetime = errors.New("sookie: cookie exceeds maximum lifetime")
+
+	// ErrReplayed is returned when a single use cookie has already been
+	// opened, see WithReplayStore.
+	ErrReplayed = errors.New("sookie: cookie already used")
+
+	// ErrCSRF is returned, possibly wrapped, when a CSRF token is missing,
+	// does not match, has expired or is bound to a different session.
+	ErrCSRF = errors.New("sookie: invalid CSRF token")
+
+	// ErrOuterMAC is returned when the outer MAC added by WithOuterMAC is
+	// missing or does not match.
+	ErrOuterMAC = errors.New("sookie: invalid outer MAC")
+
+	// ErrHeadersWritten is returned by Set when the response headers have
+	// already been written, so the cookie would be silently dropped. It can
+	// only be detected for writers which report it, see TrackWrites.
+	ErrHeadersWritten = errors.New("sookie: response headers already written")
+
+	// ErrUnregistered is returned, wrapped, when no cookie template was
+	// registered for a name, see Codec.Register.
+	ErrUnregistered = errors.New("sookie: cookie not registered")
+
+	// ErrUnknownLabel is returned, wrapped, when a LabeledKeyring has no
+	// secret with the label of a value.
+	ErrUnknownLabel = errors.New("sookie: unknown label")
+
+	// ErrUnknownKeyVersion is returned, wrapped, when a VersionedKeyring has
+	// no secret with the key version of a value, or the value has none.
+	ErrUnknownKeyVersion = errors.New("sookie: unknown key version")
+
+	// ErrZeroValue is returned when the cookie holds the zero value of its
+	// type, see WithRejectZero.
+	ErrZeroValue = errors.New("sookie: cookie holds a zero value")
+
+	// ErrStale is returned when the cookie was sealed before the cutoff, see
+	// WithNotBefore.
+	ErrStale = errors.New("sookie: cookie issued before the cutoff")
+
+	// ErrChunk is returned, wrapped, when the chunks of a chunked cookie are
+	// missing, duplicated or invalid, see GetChunked.
+	ErrChunk = errors.New("sookie: invalid chunked cookie")
+
+	// ErrTotalSize is returned, wrapped, when setting a cookie would take the
+	// cookies set on the response over the total size limit, see
+	// WithMaxTotalSize.
+	ErrTotalSize = errors.New("sookie: cookies exceed the total size limit")
+
+	// ErrTruncated is returned, wrapped, when the cookie is too short to be a
+	// sealed value, or its encoding ends part way through, which usually means
+	// it was truncated, such as by a proxy limiting header sizes. It is
+	// returned along with ErrInvalidLength or ErrDecode. A value truncated
+	// while still looking complete can not be told apart from a tampered one,
+	// and fails with the ErrDecrypt error.
+	ErrTruncated = errors.New("sookie: truncated cookie")
+
+	// ErrUnknownTag is returned, wrapped, when there is no destination for the
+	// tag of a value, see OpenTagged.
+	ErrUnknownTag = errors.New("sookie: unknown value tag")
+
+	// ErrWeakSecret is returned, wrapped, when a secret looks like it was not
+	// randomly generated, see CheckSecretEntropy.
+	ErrWeakSecret = errors.New("sookie: weak secret")
+
+	// ErrInvalidCookie is returned, wrapped, when the cookie being set would be
+	// rejected by browsers.
+	ErrInvalidCookie = errors.New("sookie: invalid cookie")
+)
@@ -0,0 +1,175 @@
+package sookie
+
+import (
+	"fmt"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// formatVersion is the first byte of every sealed message.
+const formatVersion byte = 1
+
+// formatDebug is the first byte of values stored by WithDebugPlaintext, which
+// are followed by the marshaler ID and the unencrypted marshaled wrapper.
+const formatDebug byte = 0xff
+
+// headerSize is the size of the encoded header, without the optional key
+// version.
+const headerSize = 3
+
+// minMessageSize is the size of the smallest possible sealed message, the
+// header, nonce and authentication tag around an empty ciphertext.
+const minMessageSize = headerSize + NonceSize + chacha20poly1305.Overhead
+
+// Compression algorithms stored in the header.
+const (
+	compressionNone byte = iota
+	compressionZstd
+	compressionZstdDict
+	compressionGzip
+	compressionZstdDictID
+)
+
+// flagPadded is set in the compression byte of the header when the plaintext
+// is padded, see WithPadding.
+const flagPadded byte = 0x80
+
+// flagOuterMAC is set in the compression byte of the header when the message
+// is followed by an outer MAC, see WithOuterMAC.
+const flagOuterMAC byte = 0x40
+
+// flagKeyVersion is set in the compression byte of the header when the header
+// is followed by a key version byte, see WithKeyVersion.
+const flagKeyVersion byte = 0x20
+
+// flagCleartext is set in the compression byte of the header when the header
+// ends with cleartext, prefixed by its length as a single byte, see
+// SealCleartext.
+const flagCleartext byte = 0x10
+
+// compressionFlags are the flags stored in the compression byte.
+const compressionFlags = flagPadded | flagOuterMAC | flagKeyVersion | flagCleartext
+
+// header is the cleartext prefix of every sealed message, before the nonce.
+// It describes how the message was sealed, and is authenticated as additional
+// data so it cannot be altered without failing decryption.
+type header struct {
+	marshaler   byte
+	compression byte
+	padded      bool
+	outerMAC    bool
+	versioned   bool
+	keyVersion  byte
+	dictID      byte
+	cleartext   []byte
+}
+
+// size returns the size of the encoded header.
+func (h header) size() int {
+	n := headerSize
+	if h.versioned {
+		n++
+	}
+	if h.compression == compressionZstdDictID {
+		n++
+	}
+	if h.cleartext != nil {
+		n += 1 + len(h.cleartext)
+	}
+	return n
+}
+
+// appendTo appends the encoded header to b.
+func (h header) appendTo(b []byte) []byte {
+	compression := h.compression
+	if h.padded {
+		compression |= flagPadded
+	}
+	if h.outerMAC {
+		compression |= flagOuterMAC
+	}
+	if h.versioned {
+		compression |= flagKeyVersion
+	}
+	if h.cleartext != nil {
+		compression |= flagCleartext
+	}
+	b = append(b, formatVersion, h.marshaler, compression)
+	if h.versioned {
+		b = append(b, h.keyVersion)
+	}
+	if h.compression == compressionZstdDictID {
+		b = append(b, h.dictID)
+	}
+	if h.cleartext != nil {
+		b = append(b, byte(len(h.cleartext)))
+		b = append(b, h.cleartext...)
+	}
+	return b
+}
+
+// parseHeader parses the header from the start of message, returning it
+// along with the encoded header and the rest of the message. The message must
+// be at least headerSize bytes.
+func parseHeader(message []byte) (header, []byte, []byte, error) {
+	if message[0] != formatVersion {
+		return header{}, nil, nil, fmt.Errorf("%w: unknown version %d", ErrFormat, message[0])
+	}
+	h := header{
+		marshaler:   message[1],
+		compression: message[2] &^ compressionFlags,
+		padded:      message[2]&flagPadded != 0,
+		outerMAC:    message[2]&flagOuterMAC != 0,
+	}
+	n := headerSize
+	if message[2]&flagKeyVersion != 0 {
+		if len(message) <= n {
+			return header{}, nil, nil, errTooShort
+		}
+		h.versioned, h.keyVersion = true, message[n]
+		n++
+	}
+	if h.compression == compressionZstdDictID {
+		if len(message) <= n {
+			return header{}, nil, nil, errTooShort
+		}
+		h.dictID = message[n]
+		n++
+	}
+	if message[2]&flagCleartext != 0 {
+		if len(message) <= n || len(message) <= n+int(message[n]) {
+			return header{}, nil, nil, errTooShort
+		}
+		h.cleartext = message[n+1 : n+1+int(message[n])]
+		n += 1 + len(h.cleartext)
+	}
+	return h, message[:n], message[n:], nil
+}
+
+// pad appends PKCS#7 style padding to b, so its length is a multiple of
+// blockSize. At least one byte is always added, each holding the number of
+// bytes added.
+func pad(b []byte, blockSize int) []byte {
+	n := blockSize - len(b)%blockSize
+	for range n {
+		b = append(b, byte(n))
+	}
+	return b
+}
+
+// unpad removes the padding added by pad.
+func unpad(b []byte) ([]byte, error) {
+	if len(b) == 0 {
+		return nil, fmt.Errorf("%w: invalid padding", ErrFormat)
+	}
+	n := int(b[len(b)-1])
+	if n == 0 || n > len(b) {
+		return nil, fmt.Errorf("%w: invalid padding", ErrFormat)
+	}
+	for _, v := range b[len(b)-n:] {
+		if int(v) != n {
+			return nil, fmt.Errorf("%w: invalid padding", ErrFormat)
+		}
+	}
+	return b[:len(b)-n], nil
+}
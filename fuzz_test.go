@@ -0,0 +1,42 @@
+package sookie_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/daaku/ensure"
+	"github.com/daaku/sookie"
+)
+
+func FuzzOpen(f *testing.F) {
+	sealed, err := sookie.Seal(secret, time.Time{}, given)
+	ensure.Nil(f, err)
+	f.Add(sealed)
+	f.Add(sealed[:len(sealed)/2])
+	f.Add("")
+	f.Add("invalid")
+	f.Add("AQAA")
+	f.Add("_w")
+	f.Fuzz(func(t *testing.T, raw string) {
+		var actual Flash
+		_ = sookie.OpenInto(secret, raw, &actual)
+		_, _, _ = sookie.OpenRaw(secret, raw)
+		_ = sookie.Valid(secret, raw)
+		_ = sookie.VerifyOuterMAC(secret, raw)
+		_, _ = sookie.OpenMap(secret, raw)
+	})
+}
+
+func FuzzRoundTrip(f *testing.F) {
+	f.Add("", "")
+	f.Add("success", "Hello world!")
+	f.Add("\x00", "ünïcödé")
+	f.Fuzz(func(t *testing.T, kind, content string) {
+		value := Flash{Kind: kind, Content: content}
+		sealed, err := sookie.Seal(secret, time.Time{}, value)
+		ensure.Nil(t, err)
+		actual, err := sookie.Open[Flash](secret, sealed)
+		ensure.Nil(t, err)
+		ensure.DeepEqual(t, actual, value)
+	})
+}
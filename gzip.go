@@ -0,0 +1,71 @@
+package sookie
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Compressor is the compression algorithm used when sealing values, see
+// WithCompressor. Values are opened using the algorithm recorded in their
+// header, whichever Compressor the Codec was configured with.
+type Compressor byte
+
+const (
+	// Zstd is the default Compressor, using Zstandard.
+	Zstd = Compressor(compressionZstd)
+
+	// Gzip is a Compressor using gzip, which compresses less and more slowly
+	// than Zstd, but is understood by more tools. Compression dictionaries
+	// are not supported with Gzip.
+	Gzip = Compressor(compressionGzip)
+
+	// noCompression stores values uncompressed, see SealNoCompress.
+	noCompression = Compressor(compressionNone)
+)
+
+// WithCompressor configures the compression algorithm used when sealing
+// values. It defaults to Zstd.
+func WithCompressor(compressor Compressor) Option {
+	return func(c *Codec) error {
+		if compressor != Zstd && compressor != Gzip {
+			return fmt.Errorf("sookie: unknown compressor %d", compressor)
+		}
+		c.compressor = compressor
+		return nil
+	}
+}
+
+var gzipWriterPool = sync.Pool{
+	New: func() any { return gzip.NewWriter(nil) },
+}
+
+// gzipCompress appends the gzip compressed src to dst.
+func gzipCompress(dst, src []byte) []byte {
+	b := bytes.NewBuffer(dst)
+	zw := gzipWriterPool.Get().(*gzip.Writer)
+	defer gzipWriterPool.Put(zw)
+	zw.Reset(b)
+	// writing to a bytes.Buffer can not fail
+	_, _ = zw.Write(src)
+	_ = zw.Close()
+	return b.Bytes()
+}
+
+// gzipDecompress decompresses src, refusing to decompress more than max bytes.
+func gzipDecompress(src []byte, max int) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(src))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDecompress, err)
+	}
+	uncompressed, err := io.ReadAll(io.LimitReader(zr, int64(max)+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDecompress, err)
+	}
+	if len(uncompressed) > max {
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrDecompressedSize, max)
+	}
+	return uncompressed, nil
+}
@@ -0,0 +1,53 @@
+package sookie_test
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daaku/ensure"
+	"github.com/daaku/sookie"
+)
+
+func TestGzipRoundTrip(t *testing.T) {
+	c, err := sookie.New(secret, sookie.WithCompressor(sookie.Gzip))
+	ensure.Nil(t, err)
+	value := Flash{Kind: "info", Content: strings.Repeat("compressible ", 100)}
+	raw, stats, err := c.SealWithStats(time.Time{}, value)
+	ensure.Nil(t, err)
+	ensure.True(t, stats.Compressed < stats.Marshaled)
+	message, err := base64.RawURLEncoding.DecodeString(raw)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, message[2], byte(sookie.Gzip))
+
+	var actual Flash
+	ensure.Nil(t, c.Open(raw, &actual))
+	ensure.DeepEqual(t, actual, value)
+
+	// the default Codec opens gzip values too
+	actual, err = sookie.Open[Flash](secret, raw)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, actual, value)
+}
+
+func TestGzipMaxDecompressedSize(t *testing.T) {
+	sealer, err := sookie.New(secret, sookie.WithCompressor(sookie.Gzip))
+	ensure.Nil(t, err)
+	raw, err := sealer.Seal(time.Time{}, strings.Repeat("a", 10000))
+	ensure.Nil(t, err)
+	opener, err := sookie.New(secret, sookie.WithMaxDecompressedSize(1000))
+	ensure.Nil(t, err)
+	var actual string
+	ensure.True(t, errors.Is(opener.Open(raw, &actual), sookie.ErrDecompressedSize))
+}
+
+func TestGzipInvalidOptions(t *testing.T) {
+	_, err := sookie.New(secret, sookie.WithCompressor(sookie.Compressor(42)))
+	ensure.NotNil(t, err)
+	_, err = sookie.New(secret,
+		sookie.WithCompressor(sookie.Gzip),
+		sookie.WithCompressionDict([]byte("dictionary")))
+	ensure.NotNil(t, err)
+}
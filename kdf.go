@@ -0,0 +1,62 @@
+package sookie
+
+import (
+	"crypto/hkdf"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// MinSaltSize is the minimum size of the salt used to derive a secret from a
+// passphrase.
+const MinSaltSize = 16
+
+// Argon2id parameters used by CodecFromPassphrase, following the second
+// recommended option of RFC 9106.
+const (
+	argon2Time    = 3
+	argon2Memory  = 64 * 1024
+	argon2Threads = 4
+)
+
+// DeriveSecret derives a 32 byte secret from a master secret using
+// HKDF-SHA256, so the master itself is never used as a key. The info string
+// separates secrets derived from the same master for different purposes, such
+// as one per cookie or per tenant. The master must be at least 32 bytes of
+// uniformly random data; use CodecFromPassphrase for human passphrases.
+func DeriveSecret(master []byte, info string) ([]byte, error) {
+	if len(master) < 32 {
+		return nil, fmt.Errorf("sookie: master secret must be at least 32 bytes, got %d", len(master))
+	}
+	return hkdf.Key(sha256.New, master, nil, info, 32)
+}
+
+// CodecFromMaster creates a Codec using a secret derived from the master secret
+// and info string by DeriveSecret.
+func CodecFromMaster(master []byte, info string, options ...Option) (*Codec, error) {
+	secret, err := DeriveSecret(master, info)
+	if err != nil {
+		return nil, err
+	}
+	return New(secret, options...)
+}
+
+// CodecFromPassphrase creates a Codec using a secret derived from a human
+// passphrase using Argon2id, which is deliberately slow and memory hard so the
+// passphrase is costly to brute force. The salt must be at least MinSaltSize
+// bytes, and the same passphrase and salt always derive the same secret, so
+// the salt should be stored alongside the configuration rather than
+// regenerated. Deriving the secret takes a noticeable amount of time and
+// 64 MiB of memory, so the Codec should be created once and reused.
+func CodecFromPassphrase(passphrase string, salt []byte, options ...Option) (*Codec, error) {
+	if passphrase == "" {
+		return nil, errors.New("sookie: empty passphrase")
+	}
+	if len(salt) < MinSaltSize {
+		return nil, fmt.Errorf("sookie: salt must be at least %d bytes, got %d", MinSaltSize, len(salt))
+	}
+	secret := argon2.IDKey([]byte(passphrase), salt, argon2Time, argon2Memory, argon2Threads, 32)
+	return New(secret, options...)
+}
@@ -0,0 +1,60 @@
+package sookie_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/daaku/ensure"
+	"github.com/daaku/sookie"
+)
+
+func TestCodecFromPassphrase(t *testing.T) {
+	salt := bytes.Repeat([]byte{1}, sookie.MinSaltSize)
+	c1, err := sookie.CodecFromPassphrase("correct horse battery staple", salt)
+	ensure.Nil(t, err)
+	c2, err := sookie.CodecFromPassphrase("correct horse battery staple", salt)
+	ensure.Nil(t, err)
+	raw, err := c1.Seal(time.Time{}, given)
+	ensure.Nil(t, err)
+	var actual Flash
+	ensure.Nil(t, c2.Open(raw, &actual))
+	ensure.DeepEqual(t, actual, given)
+}
+
+func TestCodecFromPassphraseInvalid(t *testing.T) {
+	_, err := sookie.CodecFromPassphrase("", make([]byte, sookie.MinSaltSize))
+	ensure.NotNil(t, err)
+	_, err = sookie.CodecFromPassphrase("passphrase", make([]byte, sookie.MinSaltSize-1))
+	ensure.NotNil(t, err)
+}
+
+func TestDeriveSecret(t *testing.T) {
+	master := bytes.Repeat([]byte{7}, 32)
+	a, err := sookie.DeriveSecret(master, "a")
+	ensure.Nil(t, err)
+	b, err := sookie.DeriveSecret(master, "b")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, len(a), 32)
+	ensure.False(t, bytes.Equal(a, b))
+	ensure.False(t, bytes.Equal(a, master))
+
+	_, err = sookie.DeriveSecret(master[:31], "a")
+	ensure.NotNil(t, err)
+}
+
+func TestCodecFromMaster(t *testing.T) {
+	master := bytes.Repeat([]byte{7}, 32)
+	c, err := sookie.CodecFromMaster(master, "session")
+	ensure.Nil(t, err)
+	raw, err := c.Seal(time.Time{}, given)
+	ensure.Nil(t, err)
+	_, err = sookie.Open[Flash](master, raw)
+	ensure.True(t, errors.Is(err, sookie.ErrDecrypt))
+	secret, err := sookie.DeriveSecret(master, "session")
+	ensure.Nil(t, err)
+	actual, err := sookie.Open[Flash](secret, raw)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, actual, given)
+}
@@ -0,0 +1,145 @@
+package sookie
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// LabeledKeyring seals and opens values using one of several secrets, each
+// identified by a label, such as the shard owning the user. Unlike a Keyring,
+// which tries each secret in turn, the label is prefixed to the sealed value
+// in cleartext, followed by a dot, so opening selects the secret directly.
+// The label comes before the prefix from WithValuePrefix, if any.
+// The label is not secret, but it is authenticated along with the value, so
+// altering it makes opening fail, even if both labels use the same secret.
+// A LabeledKeyring is safe for concurrent use by multiple goroutines.
+type LabeledKeyring struct {
+	codecs map[string]*Codec
+}
+
+// NewLabeledKeyring creates a LabeledKeyring using the secrets keyed by their
+// labels, and the given options. Labels must be non-empty, and may only
+// contain ASCII letters, digits, '-' and '_'.
+func NewLabeledKeyring(secrets map[string][]byte, options ...Option) (*LabeledKeyring, error) {
+	k := &LabeledKeyring{codecs: make(map[string]*Codec, len(secrets))}
+	for label, secret := range secrets {
+		if !validLabel(label) {
+			return nil, fmt.Errorf("sookie: invalid label %q", label)
+		}
+		c, err := New(secret, options...)
+		if err != nil {
+			return nil, fmt.Errorf("sookie: label %q: %w", label, err)
+		}
+		c.prefix = label + "." + c.prefix
+		c.label = label
+		k.codecs[label] = c
+	}
+	return k, nil
+}
+
+// validLabel reports if the label is non-empty and only contains characters
+// which can not be confused with the separator.
+func validLabel(label string) bool {
+	if label == "" {
+		return false
+	}
+	for _, r := range label {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Codec returns the Codec for the secret with the given label. Values it
+// seals are prefixed by the label, and it only opens values with the label.
+func (k *LabeledKeyring) Codec(label string) (*Codec, error) {
+	c, ok := k.codecs[label]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownLabel, label)
+	}
+	return c, nil
+}
+
+// Label returns the label of the raw value, without opening it.
+func Label(raw string) (string, error) {
+	label, _, ok := strings.Cut(raw, ".")
+	if !ok {
+		return "", fmt.Errorf("%w: missing label", ErrFormat)
+	}
+	return label, nil
+}
+
+// codecFor returns the Codec for the secret named by the label of the raw
+// value.
+func (k *LabeledKeyring) codecFor(raw string) (*Codec, string, error) {
+	label, err := Label(raw)
+	if err != nil {
+		return nil, "", err
+	}
+	c, err := k.Codec(label)
+	return c, label, err
+}
+
+// Seal encodes a value like Codec.Seal, using the secret with the given label.
+func (k *LabeledKeyring) Seal(label string, expires time.Time, value any) (string, error) {
+	c, err := k.Codec(label)
+	if err != nil {
+		return "", err
+	}
+	return c.Seal(expires, value)
+}
+
+// Open unmarshals the raw value into the value pointed to by dst like
+// Codec.Open, using the secret named by its label, which is returned.
+func (k *LabeledKeyring) Open(raw string, dst any) (string, error) {
+	return k.open("", raw, dst)
+}
+
+// open opens the raw value, bound to the cookie name if the options require
+// it.
+func (k *LabeledKeyring) open(name, raw string, dst any) (string, error) {
+	c, label, err := k.codecFor(raw)
+	if err != nil {
+		return label, err
+	}
+	_, err = c.forName(name).openInto(context.Background(), raw, dst)
+	return label, err
+}
+
+// OpenWithMetadata is like Open, but also returns the Metadata stored with
+// the value, including its label.
+func (k *LabeledKeyring) OpenWithMetadata(raw string, dst any) (Metadata, error) {
+	c, label, err := k.codecFor(raw)
+	if err != nil {
+		return Metadata{Label: label}, err
+	}
+	return c.OpenWithMetadata(raw, dst)
+}
+
+// Set sets a cookie like Codec.Set, using the secret with the given label.
+func (k *LabeledKeyring) Set(w http.ResponseWriter, label string, value any, cookie http.Cookie) error {
+	c, err := k.Codec(label)
+	if err != nil {
+		return err
+	}
+	return c.Set(w, value, cookie)
+}
+
+// Get retrieves a cookie like Codec.Get, using the secret named by its label,
+// which is returned.
+func (k *LabeledKeyring) Get(r *http.Request, name string, dst any) (string, error) {
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		if err == http.ErrNoCookie {
+			return "", err
+		}
+		return "", fmt.Errorf("sookie: failed to get cookie: %w", err)
+	}
+	return k.open(name, cookie.Value, dst)
+}
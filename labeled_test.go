@@ -0,0 +1,102 @@
+package sookie_test
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daaku/ensure"
+	"github.com/daaku/sookie"
+)
+
+func newLabeledKeyring(t *testing.T) *sookie.LabeledKeyring {
+	k, err := sookie.NewLabeledKeyring(map[string][]byte{
+		"shard-a": secret,
+		"shard-b": bytes.Repeat([]byte("b"), 32),
+	})
+	ensure.Nil(t, err)
+	return k
+}
+
+func TestLabeledKeyringSealOpen(t *testing.T) {
+	k := newLabeledKeyring(t)
+	raw, err := k.Seal("shard-b", time.Time{}, given)
+	ensure.Nil(t, err)
+	ensure.True(t, strings.HasPrefix(raw, "shard-b."))
+	label, err := sookie.Label(raw)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, label, "shard-b")
+
+	var actual Flash
+	label, err = k.Open(raw, &actual)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, label, "shard-b")
+	ensure.DeepEqual(t, actual, given)
+}
+
+func TestLabeledKeyringSwappedLabel(t *testing.T) {
+	k := newLabeledKeyring(t)
+	raw, err := k.Seal("shard-b", time.Time{}, given)
+	ensure.Nil(t, err)
+	var actual Flash
+	_, err = k.Open("shard-a."+strings.TrimPrefix(raw, "shard-b."), &actual)
+	ensure.True(t, errors.Is(err, sookie.ErrDecrypt))
+	_, err = k.Open("shard-c."+strings.TrimPrefix(raw, "shard-b."), &actual)
+	ensure.True(t, errors.Is(err, sookie.ErrUnknownLabel))
+	_, err = k.Open(strings.TrimPrefix(raw, "shard-b."), &actual)
+	ensure.True(t, errors.Is(err, sookie.ErrFormat))
+}
+
+func TestLabeledKeyringSwappedLabelSameSecret(t *testing.T) {
+	k, err := sookie.NewLabeledKeyring(map[string][]byte{
+		"shard-a": secret,
+		"shard-b": secret,
+	})
+	ensure.Nil(t, err)
+	raw, err := k.Seal("shard-b", time.Time{}, given)
+	ensure.Nil(t, err)
+	var actual Flash
+	_, err = k.Open("shard-a."+strings.TrimPrefix(raw, "shard-b."), &actual)
+	ensure.True(t, errors.Is(err, sookie.ErrDecrypt))
+	label, err := k.Open(raw, &actual)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, label, "shard-b")
+}
+
+func TestLabeledKeyringSetGet(t *testing.T) {
+	k := newLabeledKeyring(t)
+	w := httptest.NewRecorder()
+	ensure.Nil(t, k.Set(w, "shard-a", given, http.Cookie{Name: cookieName}))
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Cookie", w.Header().Get("Set-Cookie"))
+	var actual Flash
+	label, err := k.Get(r, cookieName, &actual)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, label, "shard-a")
+	ensure.DeepEqual(t, actual, given)
+}
+
+func TestLabeledKeyringInvalidLabel(t *testing.T) {
+	_, err := sookie.NewLabeledKeyring(map[string][]byte{"a.b": secret})
+	ensure.NotNil(t, err)
+	_, err = sookie.NewLabeledKeyring(map[string][]byte{"": secret})
+	ensure.NotNil(t, err)
+}
+
+func TestLabeledKeyringOpenWithMetadata(t *testing.T) {
+	k := newLabeledKeyring(t)
+	expires := time.Now().Add(time.Hour)
+	raw, err := k.Seal("shard-a", expires, given)
+	ensure.Nil(t, err)
+	var actual Flash
+	md, err := k.OpenWithMetadata(raw, &actual)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, actual, given)
+	ensure.DeepEqual(t, md.Label, "shard-a")
+	ensure.DeepEqual(t, md.Expiry.Unix(), expires.Unix())
+	ensure.False(t, md.IssuedAt.IsZero())
+}
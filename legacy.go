@@ -0,0 +1,40 @@
+package sookie
+
+import "fmt"
+
+// knownFormat reports if the byte begins a message in one of the current
+// formats.
+func knownFormat(b byte) bool {
+	return b == formatVersion || b == formatDebug || b == formatSigned
+}
+
+// acceptsLegacy reports if the Codec opens values sealed before the format
+// header was added. Those were always marshaled using MsgPack, and can not be
+// bound to a name, label, key version or outer MAC, so Codecs requiring any of
+// these reject them.
+func (c *Codec) acceptsLegacy() bool {
+	return c.marshaler.ID() == msgPackID && c.macKey == nil && !c.bindName && !c.versioned && c.label == ""
+}
+
+// openLegacy decrypts and decompresses a message sealed before the format
+// header was added, of the nonce and the ciphertext of a Zstandard frame,
+// authenticated without additional data. The message is decrypted in place.
+func (c *Codec) openLegacy(message []byte) ([]byte, error) {
+	if len(message) < NonceSize+c.aead.Overhead() {
+		return nil, errTooShort
+	}
+	began := c.stageStart()
+	nonce, ciphertext := message[:NonceSize], message[NonceSize:]
+	plaintext, err := c.aead.Open(ciphertext[:0], nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDecrypt, err)
+	}
+	c.stageDone("decrypt", began)
+	began = c.stageStart()
+	uncompressed, err := c.decompress(header{compression: compressionZstd}, plaintext)
+	if err != nil {
+		return nil, err
+	}
+	c.stageDone("decompress", began)
+	return uncompressed, nil
+}
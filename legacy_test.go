@@ -0,0 +1,61 @@
+package sookie_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/daaku/ensure"
+	"github.com/daaku/sookie"
+)
+
+// Values sealed by sookie before the format header was added, using secret.
+const (
+	legacyNoExpiry    = "-ponxSrnNsfAsWtDvN1TNF8Nn7Dyv4UhdaLnotoG2D_yoS9fecybw4QPm0kUcpPE5RWqJ2wT7SDzbjjuN47YNNHjR33Qxk5OcBAm7-LwHoer7eh3YdmX9lmIQLKB33NrYMzvUaWLMdwivDWFN_fGHCM25g"
+	legacyExpired     = "csiKS6MpjGlc2gKoWcHIQbev9kFcI6O3f-3Dd0hUDIMjsiLKfavjUKCGUjrKVw54y6RTtyGmnBhUMdYJpP_MMb7sj49yO9eoIwy8L97BabixWWhGdwEV4VZ9upE"
+	legacyVersionLike = "AT1b7y0fRxJHSxFBJHqqccixh87ise7zSfVcDjQ9jrWSdZo1IKDtLsAriyiOosJO9o7g7ktbqc9MCkKGKa9tkH7eUPgO9C63V6EABVFoM__vAMei0X3I0WTUkGW2k00sFwf_Weg"
+)
+
+func TestOpenLegacy(t *testing.T) {
+	actual, err := sookie.Open[Flash](secret, legacyNoExpiry)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, actual, Flash{Kind: "success", Content: "Sealed before the format header."})
+
+	_, err = sookie.Open[Flash](secret, legacyExpired)
+	ensure.True(t, errors.Is(err, sookie.ErrExpired))
+
+	// begins with the format version byte, so only opens once the current
+	// format fails
+	actual, err = sookie.Open[Flash](secret, legacyVersionLike)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, actual, Flash{Kind: "info", Content: "Starts like a version."})
+}
+
+func TestOpenLegacyWrongSecret(t *testing.T) {
+	other := []byte("00000000000000000000000000000000")
+	_, err := sookie.Open[Flash](other, legacyNoExpiry)
+	ensure.True(t, errors.Is(err, sookie.ErrFormat))
+	// the error depends on how the random nonce parses as a header
+	_, err = sookie.Open[Flash](other, legacyVersionLike)
+	ensure.NotNil(t, err)
+}
+
+func TestOpenLegacyRejectedWhenBound(t *testing.T) {
+	for _, option := range []sookie.Option{
+		sookie.WithMarshaler(sookie.Gob),
+		sookie.WithBindName(true),
+		sookie.WithOuterMAC([]byte("0123456789abcdef0123456789abcdef")),
+	} {
+		c, err := sookie.New(secret, option)
+		ensure.Nil(t, err)
+		var actual Flash
+		ensure.NotNil(t, c.Open(legacyNoExpiry, &actual))
+	}
+}
+
+func TestOpenLegacyLabeled(t *testing.T) {
+	k, err := sookie.NewLabeledKeyring(map[string][]byte{"shard-a": secret})
+	ensure.Nil(t, err)
+	var actual Flash
+	_, err = k.Open("shard-a."+legacyNoExpiry, &actual)
+	ensure.True(t, errors.Is(err, sookie.ErrFormat))
+}
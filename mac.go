@@ -0,0 +1,101 @@
+package sookie
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+)
+
+// macSize is the size of the truncated HMAC-SHA256 appended by WithOuterMAC.
+const macSize = 16
+
+// WithOuterMAC appends an HMAC-SHA256 of the sealed message, computed with a
+// separate key, to sealed values. This allows a party without the secret,
+// such as an edge proxy, to cheaply reject forged values using VerifyOuterMAC
+// before they reach the origin. The outer MAC only proves the value was
+// produced by a holder of the MAC key, the origin still opens it fully.
+// A Codec with this option also verifies the outer MAC when opening, and
+// rejects values without one. The key must be at least 32 bytes.
+func WithOuterMAC(macKey []byte) Option {
+	return func(c *Codec) error {
+		if len(macKey) < 32 {
+			return errors.New("sookie: outer MAC key must be at least 32 bytes")
+		}
+		c.macKey = macKey
+		return nil
+	}
+}
+
+// VerifyOuterMAC checks the outer MAC of a raw value sealed by a Codec using
+// WithOuterMAC, without needing the secret. It returns nil if the MAC is valid,
+// or the ErrOuterMAC error if it is missing or does not match. A valid MAC
+// does not mean the value can be opened, or that it has not expired. Values
+// sealed using WithEncoding or WithValuePrefix need a MACVerifier instead.
+func VerifyOuterMAC(macKey []byte, raw string) error {
+	return (&MACVerifier{macKey: macKey, c: &Codec{encoding: base64.RawURLEncoding}}).Verify(raw)
+}
+
+// MACVerifier checks the outer MAC of raw values like VerifyOuterMAC, for
+// values sealed by a Codec whose options change the raw value, such as
+// WithEncoding, WithValuePrefix or WithURLDecodeFirst. It is configured using
+// the same options as the Codec, of which only those are used.
+// A MACVerifier is safe for concurrent use by multiple goroutines.
+type MACVerifier struct {
+	macKey []byte
+	c      *Codec
+}
+
+// NewMACVerifier creates a MACVerifier using the MAC key passed to
+// WithOuterMAC, and the options of the Codec which sealed the values.
+func NewMACVerifier(macKey []byte, options ...Option) (*MACVerifier, error) {
+	if len(macKey) < 32 {
+		return nil, errors.New("sookie: outer MAC key must be at least 32 bytes")
+	}
+	c := &Codec{encoding: base64.RawURLEncoding}
+	for _, o := range options {
+		if err := o(c); err != nil {
+			return nil, err
+		}
+	}
+	return &MACVerifier{macKey: macKey, c: c}, nil
+}
+
+// Verify checks the outer MAC of the raw value like VerifyOuterMAC.
+func (v *MACVerifier) Verify(raw string) error {
+	raw, err := v.c.cutPrefix(raw)
+	if err != nil {
+		return err
+	}
+	message, err := v.c.encoding.DecodeString(raw)
+	if err != nil {
+		return decodeError(raw, err)
+	}
+	if len(message) < minMessageSize+macSize {
+		return errTooShort
+	}
+	h, _, _, err := parseHeader(message)
+	if err != nil {
+		return err
+	}
+	if !h.outerMAC {
+		return ErrOuterMAC
+	}
+	return verifyMAC(v.macKey, message)
+}
+
+// mac returns the outer MAC of message.
+func mac(macKey, message []byte) []byte {
+	m := hmac.New(sha256.New, macKey)
+	m.Write(message)
+	return m.Sum(nil)[:macSize]
+}
+
+// verifyMAC checks the outer MAC at the end of message.
+func verifyMAC(macKey, message []byte) error {
+	body, tag := message[:len(message)-macSize], message[len(message)-macSize:]
+	if !hmac.Equal(mac(macKey, body), tag) {
+		return ErrOuterMAC
+	}
+	return nil
+}
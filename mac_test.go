@@ -0,0 +1,97 @@
+package sookie_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/daaku/ensure"
+	"github.com/daaku/sookie"
+)
+
+var macKey = bytes.Repeat([]byte("m"), 32)
+
+func TestWithOuterMAC(t *testing.T) {
+	c, err := sookie.New(secret, sookie.WithOuterMAC(macKey))
+	ensure.Nil(t, err)
+	sealed, err := c.Seal(time.Time{}, given)
+	ensure.Nil(t, err)
+	ensure.Nil(t, sookie.VerifyOuterMAC(macKey, sealed))
+
+	var actual Flash
+	ensure.Nil(t, c.Open(sealed, &actual))
+	ensure.DeepEqual(t, actual, given)
+
+	// the outer MAC is skipped when opening without the MAC key
+	actual, err = sookie.Open[Flash](secret, sealed)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, actual, given)
+}
+
+func TestVerifyOuterMACWrongKey(t *testing.T) {
+	c, err := sookie.New(secret, sookie.WithOuterMAC(macKey))
+	ensure.Nil(t, err)
+	sealed, err := c.Seal(time.Time{}, given)
+	ensure.Nil(t, err)
+	err = sookie.VerifyOuterMAC(bytes.Repeat([]byte("x"), 32), sealed)
+	ensure.True(t, errors.Is(err, sookie.ErrOuterMAC))
+}
+
+func TestVerifyOuterMACTampered(t *testing.T) {
+	c, err := sookie.New(secret, sookie.WithOuterMAC(macKey))
+	ensure.Nil(t, err)
+	sealed, err := c.Seal(time.Time{}, given)
+	ensure.Nil(t, err)
+	message, err := base64.RawURLEncoding.DecodeString(sealed)
+	ensure.Nil(t, err)
+	message[len(message)/2] ^= 1
+	tampered := base64.RawURLEncoding.EncodeToString(message)
+	ensure.True(t, errors.Is(sookie.VerifyOuterMAC(macKey, tampered), sookie.ErrOuterMAC))
+	var actual Flash
+	ensure.True(t, errors.Is(c.Open(tampered, &actual), sookie.ErrOuterMAC))
+}
+
+func TestVerifyOuterMACMissing(t *testing.T) {
+	sealed, err := sookie.Seal(secret, time.Time{}, given)
+	ensure.Nil(t, err)
+	ensure.True(t, errors.Is(sookie.VerifyOuterMAC(macKey, sealed), sookie.ErrOuterMAC))
+
+	c, err := sookie.New(secret, sookie.WithOuterMAC(macKey))
+	ensure.Nil(t, err)
+	var actual Flash
+	ensure.True(t, errors.Is(c.Open(sealed, &actual), sookie.ErrOuterMAC))
+}
+
+func TestWithOuterMACShortKey(t *testing.T) {
+	_, err := sookie.New(secret, sookie.WithOuterMAC([]byte("short")))
+	ensure.StringContains(t, err.Error(), "at least 32 bytes")
+}
+
+func TestMACVerifier(t *testing.T) {
+	options := []sookie.Option{
+		sookie.WithOuterMAC(macKey),
+		sookie.WithEncoding(base64.URLEncoding),
+		sookie.WithValuePrefix("v1."),
+	}
+	c, err := sookie.New(secret, options...)
+	ensure.Nil(t, err)
+	sealed, err := c.Seal(time.Time{}, given)
+	ensure.Nil(t, err)
+	ensure.NotNil(t, sookie.VerifyOuterMAC(macKey, sealed))
+
+	v, err := sookie.NewMACVerifier(macKey, options...)
+	ensure.Nil(t, err)
+	ensure.Nil(t, v.Verify(sealed))
+
+	other, err := sookie.NewMACVerifier(bytes.Repeat([]byte("x"), 32), options...)
+	ensure.Nil(t, err)
+	ensure.True(t, errors.Is(other.Verify(sealed), sookie.ErrOuterMAC))
+	ensure.True(t, errors.Is(v.Verify(sealed[len("v1."):]), sookie.ErrFormat))
+}
+
+func TestNewMACVerifierShortKey(t *testing.T) {
+	_, err := sookie.NewMACVerifier([]byte("short"))
+	ensure.NotNil(t, err)
+}
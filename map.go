@@ -0,0 +1,100 @@
+package sookie
+
+import (
+	"fmt"
+	"math"
+)
+
+// OpenMap opens a raw value sealed from a map[string]any, for schemaless data
+// whose keys are only known at runtime. Marshalers do not preserve the exact
+// Go types of values stored in an interface, for example MsgPack picks the
+// smallest encoding for a number, so the values are normalized to a
+// predictable set of types:
+//
+//   - signed and unsigned integers become int64, or uint64 if too large
+//   - float32 becomes float64
+//   - maps become map[string]any, with keys formatted using fmt if needed
+//   - slices other than []byte become []any
+//
+// Nested maps and slices are normalized recursively. Other values, such as
+// strings, bools, []byte and nil, are returned as is. If the raw value is
+// expired, the map is still returned along with the ErrExpired error.
+func OpenMap(secret []byte, raw string) (map[string]any, error) {
+	c, err := New(secret)
+	if err != nil {
+		return nil, err
+	}
+	return c.OpenMap(raw)
+}
+
+// OpenMap opens a raw value into a normalized map like the package level
+// OpenMap function.
+func (c *Codec) OpenMap(raw string) (map[string]any, error) {
+	var m map[string]any
+	err := c.Open(raw, &m)
+	if m == nil {
+		return nil, err
+	}
+	return normalizeMap(m), err
+}
+
+// normalizeMap normalizes every value in the map, see OpenMap.
+func normalizeMap(m map[string]any) map[string]any {
+	for k, v := range m {
+		m[k] = normalize(v)
+	}
+	return m
+}
+
+// normalize returns the value converted to the predictable types documented
+// by OpenMap.
+func normalize(v any) any {
+	switch v := v.(type) {
+	case int:
+		return int64(v)
+	case int8:
+		return int64(v)
+	case int16:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case uint:
+		return normalizeUint(uint64(v))
+	case uint8:
+		return int64(v)
+	case uint16:
+		return int64(v)
+	case uint32:
+		return int64(v)
+	case uint64:
+		return normalizeUint(v)
+	case float32:
+		return float64(v)
+	case map[string]any:
+		return normalizeMap(v)
+	case map[any]any:
+		m := make(map[string]any, len(v))
+		for k, e := range v {
+			key, ok := k.(string)
+			if !ok {
+				key = fmt.Sprint(k)
+			}
+			m[key] = normalize(e)
+		}
+		return m
+	case []any:
+		for i, e := range v {
+			v[i] = normalize(e)
+		}
+		return v
+	}
+	return v
+}
+
+// normalizeUint returns the value as an int64 if it fits.
+func normalizeUint(v uint64) any {
+	if v > math.MaxInt64 {
+		return v
+	}
+	return int64(v)
+}
@@ -0,0 +1,65 @@
+package sookie_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/daaku/ensure"
+	"github.com/daaku/sookie"
+)
+
+func TestOpenMapNested(t *testing.T) {
+	value := map[string]any{
+		"name":    "flags",
+		"enabled": true,
+		"nested": map[string]any{
+			"tags": []any{"a", "b"},
+		},
+	}
+	sealed, err := sookie.Seal(secret, time.Time{}, value)
+	ensure.Nil(t, err)
+	actual, err := sookie.OpenMap(secret, sealed)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, actual, value)
+}
+
+func TestOpenMapNormalizesNumbers(t *testing.T) {
+	numbers := func() map[string]any {
+		return map[string]any{
+			"int8":    int8(-8),
+			"uint16":  uint16(1600),
+			"int64":   int64(-1 << 40),
+			"float32": float32(1.5),
+			"int":     42,
+			"huge":    uint64(1 << 63),
+		}
+	}
+	value := numbers()
+	value["nested"] = numbers()
+	value["list"] = []any{int8(-8), uint16(1600), int64(-1 << 40), float32(1.5), numbers()}
+	sealed, err := sookie.Seal(secret, time.Time{}, value)
+	ensure.Nil(t, err)
+	actual, err := sookie.OpenMap(secret, sealed)
+	ensure.Nil(t, err)
+
+	normalized := func() map[string]any {
+		return map[string]any{
+			"int8":    int64(-8),
+			"uint16":  int64(1600),
+			"int64":   int64(-1 << 40),
+			"float32": float64(1.5),
+			"int":     int64(42),
+			"huge":    uint64(1 << 63),
+		}
+	}
+	expected := normalized()
+	expected["nested"] = normalized()
+	expected["list"] = []any{int64(-8), int64(1600), int64(-1 << 40), float64(1.5), normalized()}
+	ensure.DeepEqual(t, actual, expected)
+}
+
+func TestOpenMapInvalid(t *testing.T) {
+	actual, err := sookie.OpenMap(secret, "invalid")
+	ensure.NotNil(t, err)
+	ensure.True(t, actual == nil)
+}
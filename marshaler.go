@@ -0,0 +1,85 @@
+package sookie
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+
+	"github.com/shamaton/msgpack/v2"
+)
+
+// Marshaler converts the sealed value to and from bytes. The value is always
+// wrapped in a struct with the value in the field V and the expiry in the
+// field E, and it is this wrapper that gets marshaled.
+type Marshaler interface {
+	// ID identifies the Marshaler in the format header of sealed values, so
+	// a value marshaled one way is never unmarshaled another way.
+	// IDs below 128 are reserved for the Marshalers provided by sookie.
+	ID() byte
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// Marshaler IDs stored in the format header.
+const (
+	msgPackID byte = iota
+	gobID
+	cborID
+	jsonID
+	bytesID
+)
+
+// MsgPack is the default Marshaler, using MessagePack.
+var MsgPack Marshaler = msgPackMarshaler{}
+
+// Gob is a Marshaler using encoding/gob. Like MsgPack it only encodes
+// exported fields, but it supports types implementing gob.GobEncoder or
+// encoding.BinaryMarshaler, allowing values with unexported fields to define
+// their own encoding. Values stored in interfaces must be registered with
+// gob.Register.
+var Gob Marshaler = gobMarshaler{}
+
+type funcMarshaler struct {
+	id        byte
+	marshal   func(v any) ([]byte, error)
+	unmarshal func(data []byte, v any) error
+}
+
+func (m funcMarshaler) ID() byte                           { return m.id }
+func (m funcMarshaler) Marshal(v any) ([]byte, error)      { return m.marshal(v) }
+func (m funcMarshaler) Unmarshal(data []byte, v any) error { return m.unmarshal(data, v) }
+
+type msgPackMarshaler struct{}
+
+func (msgPackMarshaler) ID() byte                           { return msgPackID }
+func (msgPackMarshaler) Marshal(v any) ([]byte, error)      { return msgpack.Marshal(v) }
+func (msgPackMarshaler) Unmarshal(data []byte, v any) error { return msgpack.Unmarshal(data, v) }
+
+type gobMarshaler struct{}
+
+func (gobMarshaler) ID() byte { return gobID }
+
+func (gobMarshaler) Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gobMarshaler) Unmarshal(data []byte, v any) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// WithMarshaler configures the Marshaler used for values. It defaults to MsgPack.
+// The Marshaler is recorded in the format header, and values sealed with a
+// different Marshaler fail to open with the ErrFormat error.
+func WithMarshaler(m Marshaler) Option {
+	return func(c *Codec) error {
+		if m == nil {
+			return errors.New("sookie: marshaler must not be nil")
+		}
+		c.marshaler = m
+		return nil
+	}
+}
@@ -0,0 +1,69 @@
+package sookie_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/daaku/ensure"
+	"github.com/daaku/sookie"
+)
+
+type Point struct {
+	x, y int
+}
+
+func (p Point) MarshalBinary() ([]byte, error) {
+	return []byte{byte(p.x), byte(p.y)}, nil
+}
+
+func (p *Point) UnmarshalBinary(data []byte) error {
+	if len(data) != 2 {
+		return errors.New("invalid point")
+	}
+	p.x, p.y = int(data[0]), int(data[1])
+	return nil
+}
+
+func TestGobMarshaler(t *testing.T) {
+	c, err := sookie.New(secret, sookie.WithMarshaler(sookie.Gob))
+	ensure.Nil(t, err)
+	raw, err := c.Seal(time.Time{}, given)
+	ensure.Nil(t, err)
+	var actual Flash
+	ensure.Nil(t, c.Open(raw, &actual))
+	ensure.DeepEqual(t, actual, given)
+}
+
+func TestGobMarshalerUnexportedFields(t *testing.T) {
+	c, err := sookie.New(secret, sookie.WithMarshaler(sookie.Gob))
+	ensure.Nil(t, err)
+	raw, err := c.Seal(time.Time{}, Point{x: 4, y: 2})
+	ensure.Nil(t, err)
+	var actual Point
+	ensure.Nil(t, c.Open(raw, &actual))
+	ensure.DeepEqual(t, actual, Point{x: 4, y: 2})
+}
+
+func TestGobMarshalerExpired(t *testing.T) {
+	c, err := sookie.New(secret, sookie.WithMarshaler(sookie.Gob))
+	ensure.Nil(t, err)
+	raw, err := c.Seal(time.Now().Add(-time.Hour), given)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, c.Valid(raw), sookie.ErrExpired)
+}
+
+func TestMarshalerMismatch(t *testing.T) {
+	c, err := sookie.New(secret, sookie.WithMarshaler(sookie.Gob))
+	ensure.Nil(t, err)
+	raw, err := c.Seal(time.Time{}, given)
+	ensure.Nil(t, err)
+	_, err = sookie.Open[Flash](secret, raw)
+	ensure.True(t, errors.Is(err, sookie.ErrFormat))
+}
+
+func TestWithMarshalerNil(t *testing.T) {
+	_, err := sookie.New(secret, sookie.WithMarshaler(nil))
+	ensure.NotNil(t, err)
+	ensure.StringContains(t, err.Error(), "sookie: marshaler must not be nil")
+}
@@ -0,0 +1,44 @@
+package sookie
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// OpenFromMetadata opens a value stored under the given key in a string map
+// such as gRPC metadata, which can be passed directly as its metadata.MD type
+// is a map[string][]string. Keys are looked up in lower case, as gRPC
+// normalizes them. If there are several values for the key, the first one
+// which opens successfully is used, and the error from the first one is
+// returned if none do. If there is no value for the key, http.ErrNoCookie is
+// returned, so a missing value is handled like a missing cookie.
+func OpenFromMetadata[V any](secret []byte, md map[string][]string, key string) (V, error) {
+	var v V
+	c, err := New(secret)
+	if err != nil {
+		return v, err
+	}
+	err = c.OpenFromMetadata(md, key, &v)
+	return v, err
+}
+
+// OpenFromMetadata opens a value stored in a string map like the package
+// level OpenFromMetadata function.
+func (c *Codec) OpenFromMetadata(md map[string][]string, key string, dst any) error {
+	key = strings.ToLower(key)
+	var first error
+	for _, raw := range md[key] {
+		err := c.openCookie(context.Background(), key, raw, dst)
+		if err == nil {
+			return nil
+		}
+		if first == nil {
+			first = err
+		}
+	}
+	if first == nil {
+		return c.report(key, http.ErrNoCookie)
+	}
+	return c.report(key, first)
+}
@@ -0,0 +1,30 @@
+package sookie_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/daaku/ensure"
+	"github.com/daaku/sookie"
+)
+
+// md mirrors the gRPC metadata.MD type.
+type md map[string][]string
+
+func TestOpenFromMetadata(t *testing.T) {
+	raw, err := sookie.Seal(secret, time.Time{}, given)
+	ensure.Nil(t, err)
+	actual, err := sookie.OpenFromMetadata[Flash](secret, md{"session": {"garbage", raw}}, "Session")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, actual, given)
+}
+
+func TestOpenFromMetadataMissing(t *testing.T) {
+	_, err := sookie.OpenFromMetadata[Flash](secret, md{}, "session")
+	ensure.True(t, errors.Is(err, http.ErrNoCookie))
+	_, err = sookie.OpenFromMetadata[Flash](secret, md{"session": {"garbage"}}, "session")
+	ensure.NotNil(t, err)
+	ensure.False(t, errors.Is(err, http.ErrNoCookie))
+}
@@ -0,0 +1,69 @@
+package sookie
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// contextKey is the context key for a value of type V opened from the cookie
+// with the given name.
+type contextKey[V any] struct {
+	name string
+}
+
+// FromContext returns the value opened from the cookie with the given name by
+// the AutoRefresh middleware. It returns false if there was no such cookie,
+// or if it could not be opened.
+func FromContext[V any](ctx context.Context, name string) (V, bool) {
+	v, ok := ctx.Value(contextKey[V]{name: name}).(V)
+	return v, ok
+}
+
+// AutoRefresh returns a middleware which opens the cookie named by the
+// template, and makes the value available to the wrapped handler via
+// FromContext. If the cookie expires within renewWithin, it is sealed again
+// with an expiry of newDuration from now and set on the response, keeping
+// active users from being logged out. The template is used to set the
+// refreshed cookie, so it should have the same Path and Domain used when it
+// was originally Set. Its MaxAge is replaced by newDuration. Cookies sealed
+// with an idle timeout, see WithIdleTimeout, are also refreshed when they
+// would become idle within renewWithin. Cookies without an expiry or idle
+// timeout are never refreshed. The cookie is opened and refreshed using a
+// Codec created with the given secret and options, which must match the ones
+// it was Set with. Refreshing is best effort, if it fails the response is left
+// untouched and the handler still sees the value. Failures to open or refresh
+// the cookie are passed to the hook set by WithErrorHook, if any.
+func AutoRefresh[V any](secret []byte, cookie http.Cookie, renewWithin, newDuration time.Duration, options ...Option) (func(http.Handler) http.Handler, error) {
+	c, err := New(secret, options...)
+	if err != nil {
+		return nil, err
+	}
+	cookie.MaxAge = int(newDuration / time.Second)
+	cookie.Expires = time.Time{}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			existing, err := r.Cookie(cookie.Name)
+			if err != nil {
+				c.report(cookie.Name, err)
+				next.ServeHTTP(w, r)
+				return
+			}
+			var v V
+			m, err := c.forName(cookie.Name).openInto(r.Context(), existing.Value, &v)
+			if err != nil {
+				c.report(cookie.Name, err)
+				next.ServeHTTP(w, r)
+				return
+			}
+			if d := c.deadline(m); d != -1 && time.Unix(d, 0).Sub(c.now()) < renewWithin {
+				if err := c.keepIdle(m).SetCtx(r.Context(), w, v, cookie); err != nil {
+					c.report(cookie.Name, fmt.Errorf("sookie: failed to refresh cookie: %w", err))
+				}
+			}
+			ctx := context.WithValue(r.Context(), contextKey[V]{name: cookie.Name}, v)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}, nil
+}
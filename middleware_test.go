@@ -0,0 +1,136 @@
+package sookie_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/daaku/ensure"
+	"github.com/daaku/sookie"
+)
+
+func serveAutoRefresh(t *testing.T, cookie string, options ...sookie.Option) (*httptest.ResponseRecorder, Flash, bool) {
+	middleware, err := sookie.AutoRefresh[Flash](secret, http.Cookie{Name: cookieName, Path: "/"},
+		time.Hour, 24*time.Hour, options...)
+	ensure.Nil(t, err)
+	var actual Flash
+	var ok bool
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actual, ok = sookie.FromContext[Flash](r.Context(), cookieName)
+	}))
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/", nil)
+	if cookie != "" {
+		r.Header.Set("Cookie", cookie)
+	}
+	handler.ServeHTTP(w, r)
+	return w, actual, ok
+}
+
+func TestAutoRefreshNearExpiry(t *testing.T) {
+	w := httptest.NewRecorder()
+	ensure.Nil(t, sookie.Set(secret, w, given, http.Cookie{Name: cookieName, MaxAge: 60}))
+	w, actual, ok := serveAutoRefresh(t, w.Header().Get("Set-Cookie"))
+	ensure.True(t, ok)
+	ensure.DeepEqual(t, actual, given)
+	set := w.Header().Get("Set-Cookie")
+	ensure.StringContains(t, set, "Max-Age=86400")
+	ensure.StringContains(t, set, "Path=/")
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Cookie", set)
+	_, expires, err := sookie.OpenRaw(secret, r.Cookies()[0].Value)
+	ensure.Nil(t, err)
+	ensure.True(t, time.Until(expires) > 23*time.Hour)
+}
+
+func TestAutoRefreshNotNearExpiry(t *testing.T) {
+	w := httptest.NewRecorder()
+	ensure.Nil(t, sookie.Set(secret, w, given, http.Cookie{Name: cookieName, MaxAge: 7200}))
+	w, actual, ok := serveAutoRefresh(t, w.Header().Get("Set-Cookie"))
+	ensure.True(t, ok)
+	ensure.DeepEqual(t, actual, given)
+	ensure.DeepEqual(t, len(w.Header().Values("Set-Cookie")), 0)
+}
+
+func TestAutoRefreshWithoutExpiry(t *testing.T) {
+	w := httptest.NewRecorder()
+	ensure.Nil(t, sookie.Set(secret, w, given, http.Cookie{Name: cookieName}))
+	w, _, ok := serveAutoRefresh(t, w.Header().Get("Set-Cookie"))
+	ensure.True(t, ok)
+	ensure.DeepEqual(t, len(w.Header().Values("Set-Cookie")), 0)
+}
+
+func TestAutoRefreshInvalid(t *testing.T) {
+	w, _, ok := serveAutoRefresh(t, cookieName+"=invalid")
+	ensure.False(t, ok)
+	ensure.DeepEqual(t, len(w.Header().Values("Set-Cookie")), 0)
+}
+
+func TestAutoRefreshMissing(t *testing.T) {
+	_, _, ok := serveAutoRefresh(t, "")
+	ensure.False(t, ok)
+}
+
+func TestAutoRefreshIdleTimeout(t *testing.T) {
+	c, err := sookie.New(secret, sookie.WithIdleTimeout(30*time.Minute))
+	ensure.Nil(t, err)
+	w := httptest.NewRecorder()
+	ensure.Nil(t, c.Set(w, given, http.Cookie{Name: cookieName, MaxAge: 7200}))
+	w, actual, ok := serveAutoRefresh(t, w.Header().Get("Set-Cookie"))
+	ensure.True(t, ok)
+	ensure.DeepEqual(t, actual, given)
+	set := w.Header().Get("Set-Cookie")
+	ensure.StringContains(t, set, "Max-Age=86400")
+
+	// the refreshed cookie keeps its idle timeout
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Cookie", set)
+	ttl, err := sookie.RemainingTTL(secret, r.Cookies()[0].Value)
+	ensure.Nil(t, err)
+	ensure.True(t, ttl <= 30*time.Minute)
+}
+
+func TestAutoRefreshOptions(t *testing.T) {
+	options := []sookie.Option{sookie.WithBindName(true), sookie.WithValuePrefix("v1.")}
+	c, err := sookie.New(secret, options...)
+	ensure.Nil(t, err)
+	w := httptest.NewRecorder()
+	ensure.Nil(t, c.Set(w, given, http.Cookie{Name: cookieName, MaxAge: 60}))
+	set := w.Header().Get("Set-Cookie")
+
+	_, _, ok := serveAutoRefresh(t, set)
+	ensure.False(t, ok)
+
+	w, actual, ok := serveAutoRefresh(t, set, options...)
+	ensure.True(t, ok)
+	ensure.DeepEqual(t, actual, given)
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Cookie", w.Header().Get("Set-Cookie"))
+	ensure.Nil(t, c.Get(r, cookieName, &actual))
+}
+
+func TestAutoRefreshReportsErrors(t *testing.T) {
+	w := httptest.NewRecorder()
+	ensure.Nil(t, sookie.Set(secret, w, given, http.Cookie{Name: cookieName, MaxAge: 60}))
+	var reported []error
+	hook := sookie.WithErrorHook(func(name string, err error) {
+		ensure.DeepEqual(t, name, cookieName)
+		reported = append(reported, err)
+	})
+	failing := sookie.WithNonceSource(func([]byte) error { return errors.New("no nonce") })
+
+	w, actual, ok := serveAutoRefresh(t, w.Header().Get("Set-Cookie"), hook, failing)
+	ensure.True(t, ok)
+	ensure.DeepEqual(t, actual, given)
+	ensure.DeepEqual(t, w.Header().Get("Set-Cookie"), "")
+	ensure.DeepEqual(t, len(reported), 1)
+	ensure.StringContains(t, reported[0].Error(), "failed to refresh cookie")
+
+	_, _, ok = serveAutoRefresh(t, cookieName+"=invalid", hook)
+	ensure.False(t, ok)
+	ensure.DeepEqual(t, len(reported), 2)
+	ensure.NotNil(t, reported[1])
+}
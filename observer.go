@@ -0,0 +1,37 @@
+package sookie
+
+import "time"
+
+// Observer is called with the time taken by each stage of sealing or opening
+// a value. The stages of sealing are "marshal", "compress", "encrypt" and
+// "encode", and those of opening are "decode", "decrypt", "decompress" and
+// "unmarshal". Values signed using WithSignOnly report "sign" and "verify"
+// instead of "encrypt" and "decrypt". Stages which are skipped, such as
+// compression of values sealed using SealNoCompress, are not reported.
+type Observer func(stage string, d time.Duration)
+
+// WithObserver configures an Observer to be called after each stage of
+// sealing or opening a value, which shows where the time is spent. Without
+// an Observer, stages are not timed at all.
+func WithObserver(o Observer) Option {
+	return func(c *Codec) error {
+		c.observer = o
+		return nil
+	}
+}
+
+// stageStart returns the start time of a stage, or the zero time if there is
+// no Observer.
+func (c *Codec) stageStart() time.Time {
+	if c.observer == nil {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+// stageDone reports the time taken by a stage which started at start.
+func (c *Codec) stageDone(stage string, start time.Time) {
+	if c.observer != nil {
+		c.observer(stage, time.Since(start))
+	}
+}
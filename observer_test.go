@@ -0,0 +1,28 @@
+package sookie_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daaku/ensure"
+	"github.com/daaku/sookie"
+)
+
+func TestWithObserver(t *testing.T) {
+	var stages []string
+	c, err := sookie.New(secret, sookie.WithObserver(func(stage string, d time.Duration) {
+		ensure.True(t, d >= 0)
+		stages = append(stages, stage)
+	}))
+	ensure.Nil(t, err)
+	value := strings.Repeat("compressible ", 100)
+	raw, err := c.Seal(time.Time{}, value)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, stages, []string{"marshal", "compress", "encrypt", "encode"})
+
+	stages = nil
+	var actual string
+	ensure.Nil(t, c.Open(raw, &actual))
+	ensure.DeepEqual(t, stages, []string{"decode", "decrypt", "decompress", "unmarshal"})
+}
@@ -0,0 +1,113 @@
+package sookie
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// SecretProvider supplies the secrets for a Keyring, such as from a secret
+// manager which rotates them. The methods are called for every operation, so
+// implementations should cache the secrets.
+type SecretProvider interface {
+	// Current returns the secret used to seal new values.
+	Current() []byte
+	// All returns every secret values may be opened with, such as the
+	// current and previous secrets during a rotation.
+	All() [][]byte
+}
+
+// Keyring seals values using the current secret from a SecretProvider, and
+// opens them using any of its secrets. A Codec is created for each distinct
+// secret, with the options the Keyring was created with, and those of the 64
+// most recently used secrets are cached, so retired secrets are dropped.
+// A Keyring is safe for concurrent use by multiple goroutines.
+type Keyring struct {
+	provider SecretProvider
+	codecs   codecCache
+}
+
+// NewKeyring creates a Keyring using the given SecretProvider and options.
+func NewKeyring(provider SecretProvider, options ...Option) *Keyring {
+	return &Keyring{provider: provider, codecs: codecCache{options: options}}
+}
+
+// Codec returns the Codec for the current secret.
+func (k *Keyring) Codec() (*Codec, error) {
+	secret := k.provider.Current()
+	if len(secret) == 0 {
+		return nil, errors.New("sookie: no current secret")
+	}
+	return k.codecs.get(secret)
+}
+
+// Seal encodes a value like Codec.Seal, using the current secret.
+func (k *Keyring) Seal(expires time.Time, value any) (string, error) {
+	c, err := k.Codec()
+	if err != nil {
+		return "", err
+	}
+	return c.Seal(expires, value)
+}
+
+// Set sets a cookie like Codec.Set, using the current secret.
+func (k *Keyring) Set(w http.ResponseWriter, value any, cookie http.Cookie) error {
+	c, err := k.Codec()
+	if err != nil {
+		return err
+	}
+	return c.Set(w, value, cookie)
+}
+
+// Open unmarshals the raw value into the value pointed to by dst like
+// Codec.Open, trying the current secret first and then the others.
+func (k *Keyring) Open(raw string, dst any) error {
+	return k.open("", raw, dst)
+}
+
+// open opens the raw value, bound to the cookie name if the options require
+// it.
+func (k *Keyring) open(name, raw string, dst any) error {
+	return k.each(func(c *Codec) error {
+		_, err := c.forName(name).openInto(context.Background(), raw, dst)
+		return err
+	})
+}
+
+// Get retrieves a cookie like Codec.Get, trying the current secret first and
+// then the others.
+func (k *Keyring) Get(r *http.Request, name string, dst any) error {
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		if err == http.ErrNoCookie {
+			return err
+		}
+		return fmt.Errorf("sookie: failed to get cookie: %w", err)
+	}
+	return k.open(name, cookie.Value, dst)
+}
+
+// each calls fn with the Codec for each secret, the current one first, until
+// it returns an error other than ErrDecrypt, which means the value was sealed
+// with a different secret.
+func (k *Keyring) each(fn func(c *Codec) error) error {
+	current := k.provider.Current()
+	secrets := append([][]byte{current}, k.provider.All()...)
+	err := fmt.Errorf("%w: no secrets", ErrDecrypt)
+	for i, secret := range secrets {
+		if len(secret) == 0 || (i > 0 && bytes.Equal(secret, current)) {
+			continue
+		}
+		c, cerr := k.codecs.get(secret)
+		if cerr != nil {
+			return cerr
+		}
+		if err = fn(c); !errors.Is(err, ErrDecrypt) {
+			return err
+		}
+	}
+	return err
+}
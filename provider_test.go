@@ -0,0 +1,67 @@
+package sookie_test
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/daaku/ensure"
+	"github.com/daaku/sookie"
+)
+
+type staticProvider struct {
+	current []byte
+	all     [][]byte
+}
+
+func (p *staticProvider) Current() []byte { return p.current }
+func (p *staticProvider) All() [][]byte   { return p.all }
+
+func TestKeyringRotation(t *testing.T) {
+	newSecret := bytes.Repeat([]byte("n"), 32)
+	provider := &staticProvider{current: secret, all: [][]byte{secret}}
+	k := sookie.NewKeyring(provider)
+	old, err := k.Seal(time.Time{}, given)
+	ensure.Nil(t, err)
+
+	provider.current = newSecret
+	provider.all = [][]byte{newSecret, secret}
+	sealed, err := k.Seal(time.Time{}, given)
+	ensure.Nil(t, err)
+	actual, err := sookie.Open[Flash](newSecret, sealed)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, actual, given)
+
+	var fromOld Flash
+	ensure.Nil(t, k.Open(old, &fromOld))
+	ensure.DeepEqual(t, fromOld, given)
+
+	provider.all = [][]byte{newSecret}
+	ensure.True(t, errors.Is(k.Open(old, &fromOld), sookie.ErrDecrypt))
+}
+
+func TestKeyringSetGet(t *testing.T) {
+	k := sookie.NewKeyring(&staticProvider{current: secret})
+	w := httptest.NewRecorder()
+	ensure.Nil(t, k.Set(w, given, http.Cookie{Name: cookieName}))
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Cookie", w.Header().Get("Set-Cookie"))
+	var actual Flash
+	ensure.Nil(t, k.Get(r, cookieName, &actual))
+	ensure.DeepEqual(t, actual, given)
+}
+
+func TestKeyringNonDecryptError(t *testing.T) {
+	k := sookie.NewKeyring(&staticProvider{current: secret, all: [][]byte{secret}})
+	var actual Flash
+	ensure.True(t, errors.Is(k.Open("garbage", &actual), sookie.ErrInvalidLength))
+}
+
+func TestKeyringNoCurrentSecret(t *testing.T) {
+	k := sookie.NewKeyring(&staticProvider{})
+	_, err := k.Seal(time.Time{}, given)
+	ensure.StringContains(t, err.Error(), "no current secret")
+}
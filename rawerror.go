@@ -0,0 +1,53 @@
+package sookie
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// WithRawOnUnmarshalError configures whether values which decrypt but fail to
+// unmarshal return a *RawError holding the decrypted marshaled wrapper, such
+// as to see that a client sent a value shaped for an older version of the
+// program. The raw bytes are the decrypted value, so they should only be
+// inspected during development or diagnostics, and never logged in
+// production.
+func WithRawOnUnmarshalError(enabled bool) Option {
+	return func(c *Codec) error {
+		c.rawOnUnmarshalError = enabled
+		return nil
+	}
+}
+
+// RawError is returned when a value fails to unmarshal using a Codec with
+// WithRawOnUnmarshalError. It wraps the ErrUnmarshal error, so it can still be
+// checked using errors.Is, and errors.As can be used to get the RawError.
+type RawError struct {
+	err error
+	raw []byte
+}
+
+// Error returns the message of the unmarshal error.
+func (e *RawError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the unmarshal error.
+func (e *RawError) Unwrap() error {
+	return e.err
+}
+
+// RawBytes returns the decrypted and decompressed marshaled wrapper which
+// failed to unmarshal. The value is under the "V" key, see the readme.
+func (e *RawError) RawBytes() []byte {
+	return e.raw
+}
+
+// unmarshalError wraps an error unmarshaling the marshaled wrapper, keeping
+// a copy of it if the options require it.
+func (c *Codec) unmarshalError(uncompressed []byte, err error) error {
+	err = fmt.Errorf("%w: %w", ErrUnmarshal, err)
+	if !c.rawOnUnmarshalError {
+		return err
+	}
+	return &RawError{err: err, raw: bytes.Clone(uncompressed)}
+}
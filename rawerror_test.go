@@ -0,0 +1,43 @@
+package sookie_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/daaku/ensure"
+	"github.com/daaku/sookie"
+)
+
+func TestWithRawOnUnmarshalError(t *testing.T) {
+	raw, err := sookie.Seal(secret, time.Time{}, "older shape")
+	ensure.Nil(t, err)
+	c, err := sookie.New(secret, sookie.WithRawOnUnmarshalError(true))
+	ensure.Nil(t, err)
+	var actual Flash
+	err = c.Open(raw, &actual)
+	ensure.True(t, errors.Is(err, sookie.ErrUnmarshal))
+	var rawErr *sookie.RawError
+	ensure.True(t, errors.As(err, &rawErr))
+	var w struct{ V string }
+	ensure.Nil(t, sookie.MsgPack.Unmarshal(rawErr.RawBytes(), &w))
+	ensure.DeepEqual(t, w.V, "older shape")
+
+	// without the option only the error is returned
+	_, err = sookie.Open[Flash](secret, raw)
+	ensure.True(t, errors.Is(err, sookie.ErrUnmarshal))
+	ensure.False(t, errors.As(err, &rawErr))
+}
+
+func TestWithRawOnUnmarshalErrorStrict(t *testing.T) {
+	raw, err := sookie.Seal(secret, time.Time{}, map[string]string{"Legacy": "x"})
+	ensure.Nil(t, err)
+	c, err := sookie.New(secret,
+		sookie.WithStrictUnmarshal(true),
+		sookie.WithRawOnUnmarshalError(true))
+	ensure.Nil(t, err)
+	var actual Flash
+	var rawErr *sookie.RawError
+	ensure.True(t, errors.As(c.Open(raw, &actual), &rawErr))
+	ensure.True(t, len(rawErr.RawBytes()) > 0)
+}
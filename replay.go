@@ -0,0 +1,53 @@
+package sookie
+
+import (
+	"errors"
+	"fmt"
+)
+
+// replayIDSize is the size of the unique ID stored in values sealed by a
+// Codec using WithReplayStore.
+const replayIDSize = 16
+
+// ReplayStore records the IDs of single use values which have been opened,
+// such as in a shared cache. Entries only need to be kept until the values
+// expire. Seen and Mark are called one after the other, so a store shared
+// between servers should make Mark fail safe, for example by using an atomic
+// set if not exists and reporting the ID as seen the next time.
+type ReplayStore interface {
+	// Seen reports if the ID has been marked.
+	Seen(id []byte) bool
+	// Mark records the ID as used.
+	Mark(id []byte)
+}
+
+// WithReplayStore makes sealed values single use. A unique ID is stored in
+// each sealed value, and opening it consults the store, returning the
+// ErrReplayed error if it was already opened, and otherwise marking it as
+// used. Values without an ID, such as those sealed by a Codec without this
+// option, are also rejected. Valid, OpenRaw and Rewrap do not use the store.
+// The value should have an expiry so the store does not grow forever.
+func WithReplayStore(store ReplayStore) Option {
+	return func(c *Codec) error {
+		if store == nil {
+			return errors.New("sookie: replay store must not be nil")
+		}
+		c.replay = store
+		return nil
+	}
+}
+
+// checkReplay checks and marks the replay ID, if the Codec uses a ReplayStore.
+func (c *Codec) checkReplay(id []byte) error {
+	if c.replay == nil {
+		return nil
+	}
+	if len(id) == 0 {
+		return fmt.Errorf("%w: missing replay ID", ErrReplayed)
+	}
+	if c.replay.Seen(id) {
+		return ErrReplayed
+	}
+	c.replay.Mark(id)
+	return nil
+}
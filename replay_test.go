@@ -0,0 +1,59 @@
+package sookie_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/daaku/ensure"
+	"github.com/daaku/sookie"
+)
+
+type memoryReplayStore struct {
+	mu   sync.Mutex
+	seen map[string]bool
+}
+
+func (s *memoryReplayStore) Seen(id []byte) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.seen[string(id)]
+}
+
+func (s *memoryReplayStore) Mark(id []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seen[string(id)] = true
+}
+
+func TestWithReplayStore(t *testing.T) {
+	store := &memoryReplayStore{seen: map[string]bool{}}
+	c, err := sookie.New(secret, sookie.WithReplayStore(store))
+	ensure.Nil(t, err)
+	first, err := c.Seal(time.Now().Add(time.Hour), given)
+	ensure.Nil(t, err)
+	second, err := c.Seal(time.Now().Add(time.Hour), given)
+	ensure.Nil(t, err)
+
+	var actual Flash
+	ensure.Nil(t, c.Open(first, &actual))
+	ensure.DeepEqual(t, actual, given)
+	ensure.True(t, errors.Is(c.Open(first, &actual), sookie.ErrReplayed))
+	ensure.Nil(t, c.Open(second, &actual))
+	ensure.Nil(t, c.Valid(first))
+}
+
+func TestWithReplayStoreMissingID(t *testing.T) {
+	c, err := sookie.New(secret, sookie.WithReplayStore(&memoryReplayStore{seen: map[string]bool{}}))
+	ensure.Nil(t, err)
+	sealed, err := sookie.Seal(secret, time.Time{}, given)
+	ensure.Nil(t, err)
+	var actual Flash
+	ensure.True(t, errors.Is(c.Open(sealed, &actual), sookie.ErrReplayed))
+}
+
+func TestWithReplayStoreNil(t *testing.T) {
+	_, err := sookie.New(secret, sookie.WithReplayStore(nil))
+	ensure.StringContains(t, err.Error(), "must not be nil")
+}
@@ -0,0 +1,125 @@
+package sookie
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// SecretFunc returns the secret to use for the given context, such as the
+// secret for the tenant the request belongs to.
+type SecretFunc func(ctx context.Context) ([]byte, error)
+
+// Resolver seals and opens values using a secret resolved from the context
+// for each operation, which keeps tenants isolated in multi-tenant apps.
+// A Codec is created for each distinct secret, with the options the Resolver
+// was created with, and those of the 64 most recently used secrets are cached.
+// A Resolver is safe for concurrent use by multiple goroutines.
+type Resolver struct {
+	secret SecretFunc
+	codecs codecCache
+}
+
+// NewResolver creates a Resolver using the given SecretFunc and options.
+func NewResolver(secret SecretFunc, options ...Option) *Resolver {
+	return &Resolver{secret: secret, codecs: codecCache{options: options}}
+}
+
+// Codec returns the Codec for the secret resolved from the context.
+func (res *Resolver) Codec(ctx context.Context) (*Codec, error) {
+	secret, err := res.secret(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("sookie: failed to resolve secret: %w", err)
+	}
+	return res.codecs.get(secret)
+}
+
+// maxCachedCodecs is the most Codecs a Keyring or Resolver keeps, after which
+// the least recently used one is dropped, so secrets which are no longer
+// used, such as rotated or per tenant secrets, do not stay in memory.
+const maxCachedCodecs = 64
+
+// codecCache creates and caches a Codec for each of the most recently used
+// secrets, using the same options.
+type codecCache struct {
+	options []Option
+	mu      sync.Mutex
+	codecs  map[string]*list.Element // of *cachedCodec
+	lru     list.List                // most recently used first
+}
+
+// cachedCodec is the Codec for a secret in a codecCache.
+type cachedCodec struct {
+	secret string
+	codec  *Codec
+}
+
+// get returns the Codec for the secret.
+func (cc *codecCache) get(secret []byte) (*Codec, error) {
+	if c, ok := cc.load(string(secret)); ok {
+		return c, nil
+	}
+	c, err := New(secret, cc.options...)
+	if err != nil {
+		return nil, err
+	}
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	// another goroutine may have created it meanwhile
+	if e, ok := cc.codecs[string(secret)]; ok {
+		cc.lru.MoveToFront(e)
+		return e.Value.(*cachedCodec).codec, nil
+	}
+	if cc.codecs == nil {
+		cc.codecs = map[string]*list.Element{}
+	}
+	cc.codecs[string(secret)] = cc.lru.PushFront(&cachedCodec{secret: string(secret), codec: c})
+	if cc.lru.Len() > maxCachedCodecs {
+		oldest := cc.lru.Remove(cc.lru.Back()).(*cachedCodec)
+		delete(cc.codecs, oldest.secret)
+	}
+	return c, nil
+}
+
+// load returns the cached Codec for the secret, if any, marking it as the
+// most recently used.
+func (cc *codecCache) load(secret string) (*Codec, bool) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	e, ok := cc.codecs[secret]
+	if !ok {
+		return nil, false
+	}
+	cc.lru.MoveToFront(e)
+	return e.Value.(*cachedCodec).codec, true
+}
+
+// Set sets a cookie like Codec.SetCtx, using the secret resolved from the
+// context.
+func (res *Resolver) Set(ctx context.Context, w http.ResponseWriter, value any, cookie http.Cookie) error {
+	c, err := res.Codec(ctx)
+	if err != nil {
+		return err
+	}
+	return c.SetCtx(ctx, w, value, cookie)
+}
+
+// Get retrieves a cookie like Codec.GetCtx, using the secret resolved from the
+// context.
+func (res *Resolver) Get(ctx context.Context, r *http.Request, name string, dst any) error {
+	c, err := res.Codec(ctx)
+	if err != nil {
+		return err
+	}
+	return c.GetCtx(ctx, r, name, dst)
+}
+
+// ResolveGet is like Get, but uses the secret resolved from the context by
+// the Resolver.
+func ResolveGet[V any](ctx context.Context, res *Resolver, r *http.Request, name string) (V, error) {
+	var v V
+	err := res.Get(ctx, r, name, &v)
+	return v, err
+}
@@ -0,0 +1,104 @@
+package sookie_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/daaku/ensure"
+	"github.com/daaku/sookie"
+)
+
+type tenantKey struct{}
+
+var tenantSecrets = map[string][]byte{
+	"a": secret,
+	"b": bytes.Repeat([]byte("b"), len(secret)),
+}
+
+func tenantResolver() *sookie.Resolver {
+	return sookie.NewResolver(func(ctx context.Context) ([]byte, error) {
+		s, ok := tenantSecrets[ctx.Value(tenantKey{}).(string)]
+		if !ok {
+			return nil, errors.New("unknown tenant")
+		}
+		return s, nil
+	})
+}
+
+func TestResolver(t *testing.T) {
+	res := tenantResolver()
+	ctx := context.WithValue(context.Background(), tenantKey{}, "a")
+	w := httptest.NewRecorder()
+	ensure.Nil(t, res.Set(ctx, w, given, http.Cookie{Name: cookieName}))
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Cookie", w.Header().Get("Set-Cookie"))
+	actual, err := sookie.ResolveGet[Flash](ctx, res, r, cookieName)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, actual, given)
+
+	first, err := res.Codec(ctx)
+	ensure.Nil(t, err)
+	second, err := res.Codec(ctx)
+	ensure.Nil(t, err)
+	ensure.True(t, first == second)
+}
+
+func TestResolverTenantIsolation(t *testing.T) {
+	res := tenantResolver()
+	ctxA := context.WithValue(context.Background(), tenantKey{}, "a")
+	ctxB := context.WithValue(context.Background(), tenantKey{}, "b")
+	w := httptest.NewRecorder()
+	ensure.Nil(t, res.Set(ctxA, w, given, http.Cookie{Name: cookieName}))
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Cookie", w.Header().Get("Set-Cookie"))
+	_, err := sookie.ResolveGet[Flash](ctxB, res, r, cookieName)
+	ensure.True(t, errors.Is(err, sookie.ErrDecrypt))
+}
+
+func TestResolverError(t *testing.T) {
+	res := tenantResolver()
+	ctx := context.WithValue(context.Background(), tenantKey{}, "c")
+	r := httptest.NewRequest("GET", "/", nil)
+	_, err := sookie.ResolveGet[Flash](ctx, res, r, cookieName)
+	ensure.NotNil(t, err)
+	ensure.StringContains(t, err.Error(), "sookie: failed to resolve secret: unknown tenant")
+}
+
+func TestResolverUsesContext(t *testing.T) {
+	res := tenantResolver()
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), tenantKey{}, "a"))
+	cancel()
+	w := httptest.NewRecorder()
+	ensure.True(t, errors.Is(res.Set(ctx, w, given, http.Cookie{Name: cookieName}), context.Canceled))
+	ensure.Nil(t, res.Set(context.WithValue(context.Background(), tenantKey{}, "a"), w, given, http.Cookie{Name: cookieName}))
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Cookie", w.Header().Get("Set-Cookie"))
+	_, err := sookie.ResolveGet[Flash](ctx, res, r, cookieName)
+	ensure.True(t, errors.Is(err, context.Canceled))
+}
+
+func TestResolverEvictsCodecs(t *testing.T) {
+	type indexKey struct{}
+	res := sookie.NewResolver(func(ctx context.Context) ([]byte, error) {
+		return fmt.Appendf(nil, "%032d", ctx.Value(indexKey{}).(int)), nil
+	})
+	codec := func(i int) *sookie.Codec {
+		c, err := res.Codec(context.WithValue(context.Background(), indexKey{}, i))
+		ensure.Nil(t, err)
+		return c
+	}
+	first, second := codec(0), codec(1)
+	for i := 2; i < 64; i++ {
+		codec(i)
+	}
+	// using 0 leaves 1 as the least recently used, which a new secret evicts
+	ensure.True(t, codec(0) == first)
+	codec(64)
+	ensure.True(t, codec(0) == first)
+	ensure.True(t, codec(1) != second)
+}
@@ -0,0 +1,36 @@
+package sookie
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// WithStrictSecurity configures whether setting a cookie which is not both
+// Secure and HttpOnly fails with the ErrInvalidCookie error. These flags are
+// easily dropped by accident, leaving cookies readable by scripts or sent over
+// plain HTTP, so enforcing them catches the mistake when the cookie is set
+// rather than in a security review. Deleting a cookie is always allowed.
+func WithStrictSecurity(strict bool) Option {
+	return func(c *Codec) error {
+		c.strictSecurity = strict
+		return nil
+	}
+}
+
+// checkSecurity returns an error if the cookie does not meet the security
+// baseline of the Codec.
+func (c *Codec) checkSecurity(cookie *http.Cookie) error {
+	if !c.strictSecurity || cookie.MaxAge < 0 {
+		return nil
+	}
+	if cookie.SameSite == http.SameSiteNoneMode && !cookie.Secure {
+		return fmt.Errorf("%w: cookie %q with SameSite=None must be Secure", ErrInvalidCookie, cookie.Name)
+	}
+	if !cookie.Secure {
+		return fmt.Errorf("%w: cookie %q must be Secure", ErrInvalidCookie, cookie.Name)
+	}
+	if !cookie.HttpOnly {
+		return fmt.Errorf("%w: cookie %q must be HttpOnly", ErrInvalidCookie, cookie.Name)
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package sookie_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/daaku/ensure"
+	"github.com/daaku/sookie"
+)
+
+func TestWithStrictSecurity(t *testing.T) {
+	c, err := sookie.New(secret, sookie.WithStrictSecurity(true))
+	ensure.Nil(t, err)
+	w := httptest.NewRecorder()
+	ensure.Nil(t, c.Set(w, given, http.Cookie{Name: cookieName, Secure: true, HttpOnly: true}))
+
+	cases := []http.Cookie{
+		{Name: cookieName, HttpOnly: true},
+		{Name: cookieName, Secure: true},
+		{Name: cookieName, HttpOnly: true, SameSite: http.SameSiteNoneMode},
+	}
+	for _, cookie := range cases {
+		w := httptest.NewRecorder()
+		ensure.True(t, errors.Is(c.Set(w, given, cookie), sookie.ErrInvalidCookie))
+		ensure.DeepEqual(t, len(w.Header().Values("Set-Cookie")), 0)
+	}
+
+	// deleting is allowed
+	w = httptest.NewRecorder()
+	ensure.Nil(t, c.Set(w, given, http.Cookie{Name: cookieName, MaxAge: -1}))
+}
@@ -0,0 +1,60 @@
+package sookie
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Session loads, saves and clears a typed value stored in a single cookie,
+// encoding the common lifecycle of a session: load it when a request starts,
+// modify it in the handler, and save it before the response is written.
+// A Session is safe for concurrent use by multiple goroutines.
+type Session[V any] struct {
+	codec  *Codec
+	cookie http.Cookie
+}
+
+// NewSession creates a Session stored in the cookie named by the template,
+// sealed using the given secret and options. The template is used when saving
+// and clearing the cookie, and may set MaxAge or Expires and the Path and
+// Domain of the cookie. Its Value must be empty.
+func NewSession[V any](secret []byte, cookie http.Cookie, options ...Option) (*Session[V], error) {
+	if cookie.Value != "" {
+		return nil, ErrValueMustBeEmpty
+	}
+	if err := checkPrefix(&cookie); err != nil {
+		return nil, err
+	}
+	c, err := New(secret, options...)
+	if err != nil {
+		return nil, err
+	}
+	return &Session[V]{codec: c, cookie: cookie}, nil
+}
+
+// Load returns the session value from the request. If the cookie is missing
+// or has expired, a zero value is returned without an error, since that is a
+// new session. Other errors, such as a tampered cookie, are returned along
+// with a zero value.
+func (s *Session[V]) Load(r *http.Request) (V, error) {
+	var v V
+	err := s.codec.GetCtx(r.Context(), r, s.cookie.Name, &v)
+	if err != nil {
+		var zero V
+		if errors.Is(err, http.ErrNoCookie) || errors.Is(err, ErrExpired) {
+			return zero, nil
+		}
+		return zero, err
+	}
+	return v, nil
+}
+
+// Save seals the session value and sets the cookie on the response.
+func (s *Session[V]) Save(w http.ResponseWriter, v V) error {
+	return s.codec.Set(w, v, s.cookie)
+}
+
+// Clear deletes the session cookie, if it was present in the request.
+func (s *Session[V]) Clear(w http.ResponseWriter, r *http.Request) {
+	Del(w, r, s.cookie)
+}
@@ -0,0 +1,67 @@
+package sookie_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/daaku/ensure"
+	"github.com/daaku/sookie"
+)
+
+type Cart struct {
+	Items []string
+}
+
+func TestSession(t *testing.T) {
+	s, err := sookie.NewSession[Cart](secret, http.Cookie{Name: cookieName, Path: "/", MaxAge: 3600})
+	ensure.Nil(t, err)
+
+	r := httptest.NewRequest("GET", "/", nil)
+	cart, err := s.Load(r)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, cart, Cart{})
+
+	cart.Items = append(cart.Items, "apple")
+	w := httptest.NewRecorder()
+	ensure.Nil(t, s.Save(w, cart))
+	ensure.StringContains(t, w.Header().Get("Set-Cookie"), "Max-Age=3600")
+
+	r = httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Cookie", w.Header().Get("Set-Cookie"))
+	cart, err = s.Load(r)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, cart.Items, []string{"apple"})
+
+	w = httptest.NewRecorder()
+	s.Clear(w, r)
+	ensure.DeepEqual(t, w.Header().Get("Set-Cookie"), cookieName+"=; Path=/; Max-Age=0")
+}
+
+func TestSessionLoadExpired(t *testing.T) {
+	s, err := sookie.NewSession[Cart](secret, http.Cookie{Name: cookieName})
+	ensure.Nil(t, err)
+	sealed, err := sookie.Seal(secret, time.Now().Add(-time.Hour), Cart{Items: []string{"apple"}})
+	ensure.Nil(t, err)
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(&http.Cookie{Name: cookieName, Value: sealed})
+	cart, err := s.Load(r)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, cart, Cart{})
+}
+
+func TestSessionLoadInvalid(t *testing.T) {
+	s, err := sookie.NewSession[Cart](secret, http.Cookie{Name: cookieName})
+	ensure.Nil(t, err)
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(&http.Cookie{Name: cookieName, Value: "garbage"})
+	_, err = s.Load(r)
+	ensure.True(t, errors.Is(err, sookie.ErrInvalidLength))
+}
+
+func TestNewSessionInvalidTemplate(t *testing.T) {
+	_, err := sookie.NewSession[Cart](secret, http.Cookie{Name: "__Host-session"})
+	ensure.True(t, errors.Is(err, sookie.ErrInvalidCookie))
+}
@@ -0,0 +1,110 @@
+package sookie
+
+import (
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+)
+
+// formatSigned is the first byte of values stored by WithSignOnly, which are
+// followed by the marshaler ID, the compression byte, the dictionary ID if
+// any, the compressed marshaled wrapper and its HMAC.
+const formatSigned byte = 0xfe
+
+// signedInfo separates the key signing values from the secret, which is also
+// the encryption key.
+const signedInfo = "sookie signed values"
+
+// WithSignOnly configures the Codec to sign values instead of encrypting them,
+// for large values which are not secret, such as public data a CDN should be
+// able to cache and inspect. Values are still marshaled and compressed, and
+// are followed by an HMAC-SHA256 computed using a key derived from the
+// secret, so they can not be altered or forged, but anyone can read them.
+// Values failing verification are rejected with the ErrDecrypt error, and a
+// Codec without this option always rejects signed values with the ErrFormat
+// error, so an encrypted cookie can not be replaced by a signed one. A Codec
+// with this option still opens encrypted values.
+func WithSignOnly(enabled bool) Option {
+	return func(c *Codec) error {
+		c.signOnly = enabled
+		return nil
+	}
+}
+
+// signKey derives the key used to sign values from the secret.
+func signKey(secret []byte) ([]byte, error) {
+	key, err := hkdf.Key(sha256.New, secret, nil, signedInfo, sha256.Size)
+	if err != nil {
+		return nil, fmt.Errorf("sookie: failed to derive signing key: %w", err)
+	}
+	return key, nil
+}
+
+// sign returns the HMAC of the message, which also covers the cookie name if
+// the value is bound to it.
+func (c *Codec) sign(message []byte) []byte {
+	m := hmac.New(sha256.New, c.signKey)
+	m.Write(c.additionalData(message))
+	return m.Sum(nil)
+}
+
+// sealSigned appends the signed message for a marshaled wrapper to dst.
+func (c *Codec) sealSigned(dst, msgp []byte, stats *Stats) []byte {
+	buf := getBuf()
+	defer putBuf(buf)
+	h := header{marshaler: c.marshaler.ID(), dictID: c.dictVersion}
+	began := c.stageStart()
+	h.compression, msgp = c.compress(buf, msgp, stats)
+	if c.compressor != noCompression {
+		c.stageDone("compress", began)
+	}
+	began = c.stageStart()
+	start := len(dst)
+	dst = append(dst, formatSigned, h.marshaler, h.compression)
+	if h.compression == compressionZstdDictID {
+		dst = append(dst, h.dictID)
+	}
+	dst = append(dst, msgp...)
+	dst = append(dst, c.sign(dst[start:])...)
+	c.stageDone("sign", began)
+	return dst
+}
+
+// openSigned verifies a signed message and returns the decompressed marshaled
+// wrapper.
+func (c *Codec) openSigned(message []byte) ([]byte, error) {
+	if !c.signOnly {
+		return nil, fmt.Errorf("%w: unencrypted signed value", ErrFormat)
+	}
+	if len(message) < headerSize+sha256.Size {
+		return nil, errTooShort
+	}
+	began := c.stageStart()
+	body, tag := message[:len(message)-sha256.Size], message[len(message)-sha256.Size:]
+	if !hmac.Equal(c.sign(body), tag) {
+		return nil, fmt.Errorf("%w: invalid signature", ErrDecrypt)
+	}
+	c.stageDone("verify", began)
+	if body[1] != c.marshaler.ID() {
+		return nil, fmt.Errorf("%w: sealed with marshaler %d, expected %d",
+			ErrFormat, body[1], c.marshaler.ID())
+	}
+	h, plaintext := header{compression: body[2]}, body[headerSize:]
+	if h.compression == compressionNone {
+		return plaintext, nil
+	}
+	if h.compression == compressionZstdDictID {
+		if len(plaintext) == 0 {
+			return nil, errTooShort
+		}
+		h.dictID, plaintext = plaintext[0], plaintext[1:]
+	}
+	began = c.stageStart()
+	uncompressed, err := c.decompress(h, plaintext)
+	if err != nil {
+		return nil, err
+	}
+	c.stageDone("decompress", began)
+	return uncompressed, nil
+}
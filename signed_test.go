@@ -0,0 +1,57 @@
+package sookie_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/daaku/ensure"
+	"github.com/daaku/sookie"
+)
+
+func TestWithSignOnly(t *testing.T) {
+	c, err := sookie.New(secret, sookie.WithSignOnly(true))
+	ensure.Nil(t, err)
+	raw, err := c.Seal(time.Time{}, given)
+	ensure.Nil(t, err)
+	message, err := base64.RawURLEncoding.DecodeString(raw)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, message[0], byte(0xfe))
+
+	var actual Flash
+	ensure.Nil(t, c.Open(raw, &actual))
+	ensure.DeepEqual(t, actual, given)
+
+	// encrypted values still open
+	encrypted, err := sookie.Seal(secret, time.Time{}, given)
+	ensure.Nil(t, err)
+	ensure.Nil(t, c.Open(encrypted, &actual))
+}
+
+func TestWithSignOnlyTampered(t *testing.T) {
+	c, err := sookie.New(secret, sookie.WithSignOnly(true))
+	ensure.Nil(t, err)
+	raw, err := c.Seal(time.Time{}, given)
+	ensure.Nil(t, err)
+	message, err := base64.RawURLEncoding.DecodeString(raw)
+	ensure.Nil(t, err)
+	message[len(message)/2] ^= 1
+	var actual Flash
+	err = c.Open(base64.RawURLEncoding.EncodeToString(message), &actual)
+	ensure.True(t, errors.Is(err, sookie.ErrDecrypt))
+
+	other, err := c.WithSecret(bytes.Repeat([]byte("o"), 32))
+	ensure.Nil(t, err)
+	ensure.True(t, errors.Is(other.Open(raw, &actual), sookie.ErrDecrypt))
+}
+
+func TestWithSignOnlyRejectedWithoutOption(t *testing.T) {
+	c, err := sookie.New(secret, sookie.WithSignOnly(true))
+	ensure.Nil(t, err)
+	raw, err := c.Seal(time.Time{}, given)
+	ensure.Nil(t, err)
+	_, err = sookie.Open[Flash](secret, raw)
+	ensure.True(t, errors.Is(err, sookie.ErrFormat))
+}
@@ -1,63 +1,104 @@
 // Package sookie provides a simple way to set and get cookies with encryption and compression.
 // It uses the XChaCha20-Poly1305 AEAD algorithm for encryption and Zstandard for compression.
 // The cookie value is base64 encoded and can be safely sent over HTTP headers.
+//
+// The package level functions and the methods of Codec, Keyring,
+// LabeledKeyring and VersionedKeyring are safe for concurrent use by multiple
+// goroutines. The Zstandard encoder and decoder shared by all of them are
+// themselves safe for concurrent use, and each call uses its own buffers.
+// Options are only applied when a Codec is created, and methods such as
+// WithSecret return a copy. The exception is Codec.Register, which adds a
+// cookie template to a set shared with the copies; it is safe to call
+// concurrently with other methods, but is best called during setup.
 package sookie
 
 import (
-	"crypto/rand"
+	"context"
+	"crypto/subtle"
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"strings"
 	"time"
 
-	"github.com/klauspost/compress/zstd"
-	"github.com/shamaton/msgpack/v2"
 	"golang.org/x/crypto/chacha20poly1305"
 )
 
-var (
-	decoder, _ = zstd.NewReader(nil)
-	encoder, _ = zstd.NewWriter(nil)
-
-	// ErrExpired is returned when the cookie has expired.
-	ErrExpired = errors.New("sookie: cookie expired")
-)
-
-type wrapper[V any] struct {
-	V V
-	E int64
-}
-
 // Seal encodes a Value. The value is encrypted and compressed
 // using the XChaCha20-Poly1305 AEAD algorithm and Zstandard compression.
 // The expiry time, if non-zero will be used when Opening the value to ensure it has not expired.
+// A zero expiry seals a value that never expires, see SealPermanent.
+// Nil and empty slices and maps are kept apart: a nil slice opens as nil, and
+// an empty slice opens as an empty, non-nil slice.
 func Seal[V any](secret []byte, expires time.Time, value V) (string, error) {
-	var e int64 = -1
-	if !expires.IsZero() {
-		e = expires.Unix()
+	c, err := New(secret)
+	if err != nil {
+		return "", err
 	}
+	return c.Seal(expires, value)
+}
+
+// CanSeal checks that the value can be marshaled by the default Marshaler,
+// without compressing or encrypting it, so values which can not be sealed,
+// such as ones containing a uintptr, are rejected before any expensive work.
+// It returns the ErrMarshal error, wrapped, if the value can not be marshaled.
+func CanSeal[V any](value V) error {
+	return canSeal(MsgPack, value)
+}
 
-	msgp, err := msgpack.Marshal(wrapper[V]{V: value, E: e})
+// SealNoCompress is like Seal, but stores the value uncompressed, which
+// avoids the cost of trying to compress values known to be incompressible,
+// such as random tokens. The header marks the value as uncompressed, so it is
+// opened like any other value.
+func SealNoCompress[V any](secret []byte, expires time.Time, value V) (string, error) {
+	c, err := New(secret)
 	if err != nil {
-		return "", fmt.Errorf("sookie: failed to marshal value: %w", err)
+		return "", err
 	}
+	return c.SealNoCompress(expires, value)
+}
+
+// SealPermanent is like Seal, but the value never expires. Opening it never
+// returns the ErrExpired error, no matter how long ago it was sealed.
+// This is equivalent to calling Seal with a zero expiry.
+func SealPermanent[V any](secret []byte, value V) (string, error) {
+	return Seal(secret, time.Time{}, value)
+}
 
-	compressed := encoder.EncodeAll(msgp, nil)
+// Stats describes the size of a sealed value at each stage of sealing.
+type Stats struct {
+	// Marshaled is the size of the marshaled value and expiry.
+	Marshaled int
+	// Compressed is the size after Zstandard compression. It is the same as
+	// Marshaled when compression did not reduce the size and was skipped.
+	Compressed int
+	// Encoded is the size of the final base64 encoded string.
+	Encoded int
+}
 
-	aead, err := chacha20poly1305.NewX(secret)
+// SealWithStats is like Seal, but also returns the size of the value at each
+// stage of sealing. This is useful to monitor how well values compress.
+func SealWithStats[V any](secret []byte, expires time.Time, value V) (string, Stats, error) {
+	c, err := New(secret)
 	if err != nil {
-		return "", fmt.Errorf("sookie: failed to create AEAD: %w", err)
+		return "", Stats{}, err
 	}
+	return c.SealWithStats(expires, value)
+}
 
-	// initial size is nonce for rand.Read, but capacity for the whole thing
-	nonce := make([]byte, chacha20poly1305.NonceSizeX,
-		chacha20poly1305.NonceSizeX+len(compressed)+chacha20poly1305.Overhead)
-	if _, err := rand.Read(nonce); err != nil {
-		return "", fmt.Errorf("sookie: failed to read nonce: %w", err)
+// EstimateSize returns the length of the sealed value for the given value,
+// without writing it anywhere. It is intended for tests asserting that a value,
+// such as a session, stays within the size budget of a cookie. The estimate
+// assumes the value expires, which takes a few more bytes than a value which
+// does not, and excludes the cookie name and attributes.
+func EstimateSize[V any](secret []byte, value V) (int, error) {
+	c, err := New(secret)
+	if err != nil {
+		return 0, err
 	}
-	ciphertext := aead.Seal(nonce, nonce, compressed, nil)
-	return base64.RawURLEncoding.EncodeToString(ciphertext), nil
+	return c.EstimateSize(value)
 }
 
 // Open retrieves a value from the raw encrypted string.
@@ -65,34 +106,168 @@ func Seal[V any](secret []byte, expires time.Time, value V) (string, error) {
 // The raw value is unmarshaled into the given type V.
 // If the raw value is expired, the ErrExpired error is returned.
 func Open[V any](secret []byte, raw string) (V, error) {
-	var w wrapper[V]
-	message, err := base64.RawURLEncoding.DecodeString(raw)
+	var v V
+	err := OpenInto(secret, raw, &v)
+	return v, err
+}
+
+// OpenInto is like Open, but unmarshals into the value pointed to by dst.
+// It mirrors json.Unmarshal and is useful when the type of the value is only
+// known at runtime. If dst is not a non-nil pointer, an error is returned.
+// If the raw value is expired, dst is still populated and the ErrExpired error
+// is returned.
+func OpenInto(secret []byte, raw string, dst any) error {
+	c, err := New(secret)
 	if err != nil {
-		return w.V, fmt.Errorf("sookie: failed to decode cookie: %w", err)
+		return err
+	}
+	return c.Open(raw, dst)
+}
+
+// Metadata is stored along with every sealed value.
+type Metadata struct {
+	// IssuedAt is when the value was sealed, in whole seconds. It is zero for
+	// values sealed before it was stored.
+	IssuedAt time.Time
+	// Expiry is the expiry of the value, in whole seconds. It is zero if the
+	// value never expires.
+	Expiry time.Time
+	// IdleTimeout is the idle timeout stored in the value, or zero if there is
+	// none, see WithIdleTimeout.
+	IdleTimeout time.Duration
+	// Label is the label of the secret the value was sealed with, for values
+	// opened using a LabeledKeyring, or empty otherwise.
+	Label string
+}
+
+// metadata converts the stored metadata to the exported form.
+func (m meta) metadata() Metadata {
+	var md Metadata
+	if m.created != 0 {
+		md.IssuedAt = time.Unix(m.created, 0)
 	}
-	if len(message) < chacha20poly1305.NonceSizeX {
-		return w.V, errors.New("sookie: invalid cookie length")
+	if m.expiryMilli != 0 {
+		md.Expiry = time.UnixMilli(m.expiryMilli)
+	} else if m.expiry != -1 {
+		md.Expiry = time.Unix(m.expiry, 0)
 	}
-	nonce, ciphertext := message[:chacha20poly1305.NonceSizeX], message[chacha20poly1305.NonceSizeX:]
-	aead, err := chacha20poly1305.NewX(secret)
+	md.IdleTimeout = time.Duration(m.idle) * time.Second
+	return md
+}
+
+// OpenWithMetadata is like Open, but also returns the Metadata stored with
+// the value, such as when it was sealed. If the raw value is expired, the
+// value and Metadata are still returned along with the ErrExpired error.
+func OpenWithMetadata[V any](secret []byte, raw string) (V, Metadata, error) {
+	var v V
+	c, err := New(secret)
 	if err != nil {
-		return w.V, fmt.Errorf("sookie: failed to create AEAD: %w", err)
+		return v, Metadata{}, err
 	}
-	plaintext, err := aead.Open(nil, nonce, ciphertext, nil)
+	md, err := c.OpenWithMetadata(raw, &v)
+	return v, md, err
+}
+
+// GetWithMetadata is like Get, but also returns the Metadata stored with the
+// value, such as when it was sealed.
+func GetWithMetadata[V any](secret []byte, r *http.Request, name string) (V, Metadata, error) {
+	var v V
+	c, err := New(secret)
 	if err != nil {
-		return w.V, fmt.Errorf("sookie: failed to decrypt cookie: %w", err)
+		return v, Metadata{}, err
 	}
-	uncompressed, err := decoder.DecodeAll(plaintext, nil)
+	cookie, err := r.Cookie(name)
 	if err != nil {
-		return w.V, fmt.Errorf("sookie: failed to decompress cookie: %w", err)
+		return v, Metadata{}, err
 	}
-	if err := msgpack.Unmarshal(uncompressed, &w); err != nil {
-		return w.V, fmt.Errorf("sookie: failed to unmarshal cookie: %w", err)
+	md, err := c.OpenWithMetadata(cookie.Value, &v)
+	return v, md, err
+}
+
+// OpenRaw decrypts and decompresses the raw value, returning the marshaled
+// wrapper containing the value along with its expiry, without unmarshaling
+// the value into a Go type. This is intended for debugging values that fail
+// to unmarshal. The expiry is zero if the value never expires. If the value
+// is expired, it is still returned along with the ErrExpired error.
+func OpenRaw(secret []byte, raw string) ([]byte, time.Time, error) {
+	c, err := New(secret)
+	if err != nil {
+		return nil, time.Time{}, err
 	}
-	if w.E != -1 && time.Now().Unix() > w.E {
-		return w.V, ErrExpired
+	return c.OpenRaw(raw)
+}
+
+// Valid checks that the raw value is authentic and has not expired, without
+// unmarshaling the value itself. It returns nil if the value is valid, the
+// ErrExpired error if it has expired, or the error describing why it could not
+// be opened.
+func Valid(secret []byte, raw string) error {
+	c, err := New(secret)
+	if err != nil {
+		return err
+	}
+	return c.Valid(raw)
+}
+
+// Has reports if the request has a cookie with the given name which opens and
+// has not expired, such as to only run checks needing a session when one is
+// present. Like Valid, the value itself is not unmarshaled, and the reason a
+// cookie does not open is not reported.
+func Has(secret []byte, r *http.Request, name string) bool {
+	c, err := New(secret)
+	if err != nil {
+		return false
+	}
+	return c.Has(r, name)
+}
+
+// ValidateBatch checks many raw values like Valid, such as in an offline job
+// finding which values need to be sealed again, returning the result for each
+// raw value at the same index. The secret is only set up once.
+func ValidateBatch(secret []byte, raws []string) []error {
+	c, err := New(secret)
+	if err != nil {
+		errs := make([]error, len(raws))
+		for i := range errs {
+			errs[i] = err
+		}
+		return errs
+	}
+	return c.ValidateBatch(raws)
+}
+
+// Permanent is the remaining time returned by RemainingTTL for values which
+// never expire. It is the largest Duration, so it can be passed to min along
+// with other limits.
+const Permanent = time.Duration(math.MaxInt64)
+
+// RemainingTTL returns the time left until the raw value expires, truncated
+// to whole seconds, or Permanent if it never expires. It is useful to keep
+// caches, such as the max-age of a Cache-Control header, from outliving the
+// value. If the raw value is expired, zero is returned along with the
+// ErrExpired error.
+func RemainingTTL(secret []byte, raw string) (time.Duration, error) {
+	c, err := New(secret)
+	if err != nil {
+		return 0, err
 	}
-	return w.V, nil
+	return c.RemainingTTL(raw)
+}
+
+// Rewrap re-encrypts a raw value sealed with oldSecret using newSecret.
+// The value and the stored expiry are preserved exactly, which makes it
+// suitable for key rotation. Unlike sealing the value again, the expiry is
+// not recomputed. If the raw value is expired, the ErrExpired error is returned.
+func Rewrap(oldSecret, newSecret []byte, raw string) (string, error) {
+	from, err := New(oldSecret)
+	if err != nil {
+		return "", err
+	}
+	to, err := New(newSecret)
+	if err != nil {
+		return "", err
+	}
+	return to.Rewrap(from, raw)
 }
 
 // Set sets a cookie with the given value. The value is encrypted and compressed
@@ -102,49 +277,243 @@ func Open[V any](secret []byte, raw string) (V, error) {
 // MaxAge takes precedence over Expires.
 // The http.Cookie `Value` field must be empty and the passed in value will me marshaled and used instead.
 // The cookie will be deleted if MaxAge is less than 0 (and an empty value will be sent).
+// Cookies named with the __Host- or __Secure- prefix must carry the attributes
+// browsers require for those prefixes, otherwise an error is returned.
+// A Set-Cookie header for the same cookie, one with the same Name, Path and
+// Domain, already in the response is replaced rather than sending both.
+// If the response writer reports that the headers were already written, the
+// ErrHeadersWritten error is returned, see TrackWrites.
 func Set[V any](secret []byte, w http.ResponseWriter, value V, cookie http.Cookie) error {
-	if cookie.Value != "" {
-		return errors.New("sookie: cookie value must be empty")
+	return SetCtx(context.Background(), secret, w, value, cookie)
+}
+
+// SetUntil is like Set, but seals the value with the given absolute expiry and
+// sets the MaxAge of the cookie to match it, so the embedded and browser
+// expiry never drift. The MaxAge and Expires of the template are replaced.
+// If the expiry has already passed, the ErrExpired error is returned and no
+// cookie is set.
+func SetUntil[V any](secret []byte, w http.ResponseWriter, value V, expires time.Time, cookie http.Cookie) error {
+	c, err := New(secret)
+	if err != nil {
+		return err
 	}
+	return c.SetUntil(w, value, expires, cookie)
+}
 
-	// special case delete cookie
-	if cookie.MaxAge < 0 {
-		http.SetCookie(w, &cookie)
-		return nil
+// SealCookie seals the value into the cookie like Set, but returns the
+// Set-Cookie header value instead of adding it to a response, for code which
+// builds responses without an http.ResponseWriter.
+func SealCookie[V any](secret []byte, value V, cookie http.Cookie) (string, error) {
+	c, err := New(secret)
+	if err != nil {
+		return "", err
 	}
+	return c.SealCookie(value, cookie)
+}
 
-	var expires time.Time
-	if cookie.MaxAge > 0 {
-		expires = time.Now().Add(time.Duration(cookie.MaxAge) * time.Second)
-	} else if !cookie.Expires.IsZero() {
-		expires = cookie.Expires
+// MakeCookie seals the value into a copy of the template like Set, but returns
+// the resulting cookie instead of adding it to a response, for frameworks with
+// their own cookie types. The Value of the returned cookie holds the sealed
+// value, and its MaxAge and Expires are those Set would use.
+func MakeCookie[V any](secret []byte, value V, template http.Cookie) (*http.Cookie, error) {
+	c, err := New(secret)
+	if err != nil {
+		return nil, err
 	}
+	return c.MakeCookie(value, template)
+}
 
-	encoded, err := Seal(secret, expires, value)
+// SetCtx is like Set, but checks the context for cancellation before the
+// expensive steps of sealing the value, and passes it to the Tracer if one is
+// configured on a Codec.
+func SetCtx[V any](ctx context.Context, secret []byte, w http.ResponseWriter, value V, cookie http.Cookie) error {
+	c, err := New(secret)
 	if err != nil {
 		return err
 	}
-	cookie.Value = encoded
+	return c.SetCtx(ctx, w, value, cookie)
+}
 
-	if err := cookie.Valid(); err != nil {
-		return fmt.Errorf("sookie: invalid cookie: %w", err)
+// SetWithSize is like Set, but also returns the length in bytes of the
+// Set-Cookie header value that was added to the response. Browsers commonly
+// drop cookies larger than 4096 bytes, so this is useful to monitor how close
+// cookies are to that limit.
+func SetWithSize[V any](secret []byte, w http.ResponseWriter, value V, cookie http.Cookie) (int, error) {
+	c, err := New(secret)
+	if err != nil {
+		return 0, err
+	}
+	return c.SetWithSize(w, value, cookie)
+}
+
+// ValidateSecrets checks a set of secrets, such as those used for key rotation,
+// returning an error listing every problem found. Each secret must be 32 bytes
+// and no secret may appear more than once. This is intended to be called at
+// startup so a mistyped secret fails early rather than when it is first used.
+func ValidateSecrets(secrets [][]byte) error {
+	if len(secrets) == 0 {
+		return errors.New("sookie: no secrets")
 	}
+	var errs []error
+	seen := map[string]int{}
+	for i, secret := range secrets {
+		if len(secret) != chacha20poly1305.KeySize {
+			errs = append(errs, fmt.Errorf("sookie: secret %d must be %d bytes, got %d",
+				i, chacha20poly1305.KeySize, len(secret)))
+		}
+		if j, ok := seen[string(secret)]; ok {
+			errs = append(errs, fmt.Errorf("sookie: secret %d is a duplicate of secret %d", i, j))
+			continue
+		}
+		seen[string(secret)] = i
+	}
+	return errors.Join(errs...)
+}
 
-	http.SetCookie(w, &cookie)
+const (
+	hostPrefix   = "__Host-"
+	securePrefix = "__Secure-"
+)
+
+// checkPrefix enforces the browser rules for the __Host- and __Secure- cookie
+// name prefixes. Browsers silently reject cookies that violate them.
+func checkPrefix(cookie *http.Cookie) error {
+	switch {
+	case strings.HasPrefix(cookie.Name, hostPrefix):
+		if !cookie.Secure {
+			return fmt.Errorf("%w: __Host- cookie must be Secure", ErrInvalidCookie)
+		}
+		if cookie.Path != "/" {
+			return fmt.Errorf("%w: __Host- cookie must have Path set to /", ErrInvalidCookie)
+		}
+		if cookie.Domain != "" {
+			return fmt.Errorf("%w: __Host- cookie must not have a Domain", ErrInvalidCookie)
+		}
+	case strings.HasPrefix(cookie.Name, securePrefix):
+		if !cookie.Secure {
+			return fmt.Errorf("%w: __Secure- cookie must be Secure", ErrInvalidCookie)
+		}
+	}
 	return nil
 }
 
+// HostTemplate returns a cookie template for a __Host- prefixed cookie with
+// the given name. The prefix is added if name does not already have it.
+// The template is Secure, HttpOnly, has Path set to / and SameSite set to Lax,
+// which satisfies the browser rules for the prefix.
+func HostTemplate(name string) http.Cookie {
+	if !strings.HasPrefix(name, hostPrefix) {
+		name = hostPrefix + name
+	}
+	return http.Cookie{
+		Name:     name,
+		Path:     "/",
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
 var zeroTime time.Time
 
 // Del deletes a cookie with the given name from the response, if it was present in the request.
+// A Set-Cookie header for the same cookie already in the response, such as
+// from an earlier call to Set, is replaced by the deletion, even if the
+// request did not have the cookie. Browsers only delete the cookie if
+// the Path and Domain of the template match the ones it was set with, and
+// ignore the deletion of __Host- and __Secure- cookies which are not Secure,
+// see DelMatching, which checks the template.
 func Del(w http.ResponseWriter, r *http.Request, cookie http.Cookie) {
-	if len(r.CookiesNamed(cookie.Name)) != 0 {
+	if len(r.CookiesNamed(cookie.Name)) != 0 || pendingCookie(w, &cookie) {
 		c := cookie
 		c.Value = ""
 		c.Expires = zeroTime
 		c.MaxAge = -1
-		http.SetCookie(w, &c)
+		setCookie(w, &c)
+	}
+}
+
+// DelMatching is like Del, but returns an error instead of sending a deletion
+// the browser would ignore. The template must have a Path, since the default
+// Path depends on the URL the cookie was set from, and should be the same
+// template used to Set the cookie so the Path and Domain match. Cookies with
+// the __Host- and __Secure- prefixes must follow the rules for them.
+func DelMatching(w http.ResponseWriter, r *http.Request, cookie http.Cookie) error {
+	if cookie.Path == "" {
+		return fmt.Errorf("%w: the Path must match the one the cookie was set with", ErrInvalidCookie)
+	}
+	if err := checkPrefix(&cookie); err != nil {
+		return err
+	}
+	Del(w, r, cookie)
+	return nil
+}
+
+// DelAll deletes cookies using each of the given templates, if a cookie with
+// the template's name was present in the request. Browsers only delete a cookie
+// when the Path and Domain match the ones it was set with, so templates can
+// vary those to clear a cookie that may have been set under several scopes.
+func DelAll(w http.ResponseWriter, r *http.Request, templates ...http.Cookie) {
+	for _, cookie := range templates {
+		Del(w, r, cookie)
+	}
+}
+
+// Touch re-sends a cookie with the given name, if it was present in the request,
+// using the value from the request verbatim. This extends the lifetime of the
+// cookie in the browser without re-sealing it, so the value and the expiry
+// embedded in it are unchanged. The cookie template should carry the new MaxAge
+// or Expires, as well as the same Path and Domain used when it was Set.
+func Touch(w http.ResponseWriter, r *http.Request, cookie http.Cookie) {
+	existing, err := r.Cookie(cookie.Name)
+	if err != nil {
+		return
 	}
+	c := cookie
+	c.Value = existing.Value
+	setCookie(w, &c)
+}
+
+// ReissueAttributes re-sends the cookie with the given name using the new
+// template, such as to add Secure or tighten SameSite on cookies already
+// issued, copying the value from the request verbatim without opening it.
+// The template Name is replaced by name, and its Value must be empty. The
+// expiry embedded in the value is unchanged, so the template should not keep
+// the cookie longer than that. Browsers identify cookies by their Name, Path
+// and Domain, so if the template changes the Path or Domain the cookie should
+// also be deleted from the old scope using Del. The http.ErrNoCookie error is
+// returned if the cookie was not present in the request.
+func ReissueAttributes(w http.ResponseWriter, r *http.Request, name string, template http.Cookie) error {
+	if template.Value != "" {
+		return ErrValueMustBeEmpty
+	}
+	existing, err := r.Cookie(name)
+	if err != nil {
+		return err
+	}
+	template.Name = name
+	template.Value = existing.Value
+	if err := checkPrefix(&template); err != nil {
+		return err
+	}
+	if err := template.Valid(); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidCookie, err)
+	}
+	setCookie(w, &template)
+	return nil
+}
+
+// List returns the names of the cookies in the request which look like sealed
+// values. This is a structural check that does not need the secret: the value
+// must be valid base64, long enough to hold a sealed value and start with a
+// known format version. It may include false positives, cookies which happen
+// to look like sealed values, as well as cookies sealed with other secrets.
+// Each name is returned once, in the order it first appears. Only values
+// sealed using the default options are listed, use Codec.List for values with
+// a prefix, label or another encoding. Values sealed before the format header
+// was added have no structure to check, and are never listed.
+func List(r *http.Request) []string {
+	return (&Codec{encoding: base64.RawURLEncoding}).List(r)
 }
 
 // Get retrieves a cookie with the given name from the request.
@@ -153,13 +522,73 @@ func Del(w http.ResponseWriter, r *http.Request, cookie http.Cookie) {
 // If the cookie is not found, the http.ErrNoCookie error is returned.
 // If the cookie is expired, the ErrExpired error is returned.
 func Get[V any](secret []byte, r *http.Request, name string) (V, error) {
-	cookie, err := r.Cookie(name)
+	return GetCtx[V](context.Background(), secret, r, name)
+}
+
+// GetCtx is like Get, but checks the context for cancellation before the
+// expensive steps of opening the value, and passes it to the Tracer if one is
+// configured on a Codec.
+func GetCtx[V any](ctx context.Context, secret []byte, r *http.Request, name string) (V, error) {
+	var v V
+	c, err := New(secret)
 	if err != nil {
-		var v V
-		if err == http.ErrNoCookie {
-			return v, err
-		}
-		return v, fmt.Errorf("sookie: failed to get cookie: %w", err)
+		return v, err
 	}
-	return Open[V](secret, cookie.Value)
+	err = c.GetCtx(ctx, r, name, &v)
+	return v, err
+}
+
+// GetAndDelete is like Get, but also deletes the cookie from the response if
+// it was present in the request, which gives read once semantics for values
+// such as flash messages. The cookie template is used to delete the cookie, so
+// it should have the same Path and Domain used when it was Set. The cookie is
+// deleted even if its value could not be opened.
+func GetAndDelete[V any](secret []byte, w http.ResponseWriter, r *http.Request, cookie http.Cookie) (V, error) {
+	var v V
+	c, err := New(secret)
+	if err != nil {
+		return v, err
+	}
+	err = c.GetAndDelete(w, r, cookie, &v)
+	return v, err
+}
+
+// GetFromCookies is like Get, but retrieves the cookie from a list of cookies
+// instead of a request. This is useful for clients, for example with the
+// cookies from http.CookieJar.Cookies or http.Response.Cookies.
+func GetFromCookies[V any](secret []byte, cookies []*http.Cookie, name string) (V, error) {
+	var v V
+	c, err := New(secret)
+	if err != nil {
+		return v, err
+	}
+	err = c.GetFromCookies(cookies, name, &v)
+	return v, err
+}
+
+// GetAny is like Get, but tries every cookie with the given name in the request.
+// Clients may send several cookies with the same name when they were set with
+// overlapping Domain or Path scopes, and the first one may be stale.
+// The value from the first cookie that opens successfully is returned.
+// If none do, the error from the first cookie is returned.
+// If the cookie is not found, the http.ErrNoCookie error is returned.
+func GetAny[V any](secret []byte, r *http.Request, name string) (V, error) {
+	var v V
+	c, err := New(secret)
+	if err != nil {
+		return v, err
+	}
+	if err := c.GetAny(r, name, &v); err != nil {
+		var zero V
+		return zero, err
+	}
+	return v, nil
+}
+
+// SecureEqual reports if the two sealed values are equal, in constant time
+// for values of the same length. This is intended for comparing values from
+// untrusted sources, such as the double submit pattern for CSRF tokens, where
+// comparing with == would leak how much of the values match through timing.
+func SecureEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
 }
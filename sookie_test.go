@@ -2,8 +2,13 @@ package sookie_test
 
 import (
 	"bytes"
+	"encoding/base64"
+	"errors"
 	"net/http"
+	"net/http/cookiejar"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 	"time"
 
@@ -76,6 +81,7 @@ func TestSetErrorWithValue(t *testing.T) {
 	})
 	ensure.NotNil(t, err)
 	ensure.StringContains(t, err.Error(), "sookie: cookie value must be empty")
+	ensure.True(t, errors.Is(err, sookie.ErrValueMustBeEmpty))
 }
 
 func TestSetErrorWithUnsupportedMarshal(t *testing.T) {
@@ -83,6 +89,7 @@ func TestSetErrorWithUnsupportedMarshal(t *testing.T) {
 	err := sookie.Set(secret, w, struct{ P uintptr }{}, http.Cookie{Name: cookieName})
 	ensure.NotNil(t, err)
 	ensure.StringContains(t, err.Error(), "sookie: failed to marshal value")
+	ensure.True(t, errors.Is(err, sookie.ErrMarshal))
 }
 
 func TestSetErrorWithInvalidSecret(t *testing.T) {
@@ -90,6 +97,7 @@ func TestSetErrorWithInvalidSecret(t *testing.T) {
 	err := sookie.Set([]byte("hello world"), w, Flash{}, http.Cookie{Name: cookieName})
 	ensure.NotNil(t, err)
 	ensure.StringContains(t, err.Error(), "sookie: failed to create AEAD")
+	ensure.True(t, errors.Is(err, sookie.ErrAEAD))
 }
 
 func TestSetErrorWithEmptySecret(t *testing.T) {
@@ -97,6 +105,7 @@ func TestSetErrorWithEmptySecret(t *testing.T) {
 	err := sookie.Set([]byte(""), w, Flash{}, http.Cookie{Name: cookieName})
 	ensure.NotNil(t, err)
 	ensure.StringContains(t, err.Error(), "sookie: failed to create AEAD")
+	ensure.True(t, errors.Is(err, sookie.ErrAEAD))
 }
 
 func TestSetErrorWithInvalidCookie(t *testing.T) {
@@ -107,6 +116,7 @@ func TestSetErrorWithInvalidCookie(t *testing.T) {
 	})
 	ensure.NotNil(t, err)
 	ensure.StringContains(t, err.Error(), "sookie: invalid cookie")
+	ensure.True(t, errors.Is(err, sookie.ErrInvalidCookie))
 }
 
 func TestErrorWithExpired(t *testing.T) {
@@ -149,6 +159,7 @@ func TestGetErrorInvalidCookie(t *testing.T) {
 	_, err := sookie.Get[Flash](secret, r, cookieName)
 	ensure.NotNil(t, err)
 	ensure.StringContains(t, err.Error(), "sookie: invalid cookie length")
+	ensure.True(t, errors.Is(err, sookie.ErrInvalidLength))
 }
 
 func TestGetErrorDecode(t *testing.T) {
@@ -157,6 +168,7 @@ func TestGetErrorDecode(t *testing.T) {
 	_, err := sookie.Get[Flash](secret, r, cookieName)
 	ensure.NotNil(t, err)
 	ensure.StringContains(t, err.Error(), "sookie: failed to decode cookie")
+	ensure.True(t, errors.Is(err, sookie.ErrDecode))
 }
 
 func TestGetErrorWithEmptySecret(t *testing.T) {
@@ -168,6 +180,7 @@ func TestGetErrorWithEmptySecret(t *testing.T) {
 	_, err = sookie.Get[Flash]([]byte(""), r, cookieName)
 	ensure.NotNil(t, err)
 	ensure.StringContains(t, err.Error(), "sookie: failed to create AEAD")
+	ensure.True(t, errors.Is(err, sookie.ErrAEAD))
 }
 
 func TestSetGetSecretMismatch(t *testing.T) {
@@ -180,6 +193,18 @@ func TestSetGetSecretMismatch(t *testing.T) {
 	_, err = sookie.Get[Flash](invalidSecret, r, cookieName)
 	ensure.NotNil(t, err)
 	ensure.StringContains(t, err.Error(), "sookie: failed to decrypt cookie")
+	ensure.True(t, errors.Is(err, sookie.ErrDecrypt))
+}
+
+func TestOpenErrorTampered(t *testing.T) {
+	raw, err := sookie.Seal(secret, time.Time{}, given)
+	ensure.Nil(t, err)
+	message, err := base64.RawURLEncoding.DecodeString(raw)
+	ensure.Nil(t, err)
+	message[len(message)-1] ^= 1
+	_, err = sookie.Open[Flash](secret, base64.RawURLEncoding.EncodeToString(message))
+	ensure.NotNil(t, err)
+	ensure.True(t, errors.Is(err, sookie.ErrDecrypt))
 }
 
 func TestSetGetUnmarshalMismatch(t *testing.T) {
@@ -191,6 +216,7 @@ func TestSetGetUnmarshalMismatch(t *testing.T) {
 	_, err = sookie.Get[int](secret, r, cookieName)
 	ensure.NotNil(t, err)
 	ensure.StringContains(t, err.Error(), "sookie: failed to unmarshal cookie")
+	ensure.True(t, errors.Is(err, sookie.ErrUnmarshal))
 }
 
 func TestDelExists(t *testing.T) {
@@ -207,3 +233,677 @@ func TestDelMissing(t *testing.T) {
 	sookie.Del(w, r, http.Cookie{Name: cookieName})
 	ensure.DeepEqual(t, len(w.Header().Values("Set-Cookie")), 0)
 }
+
+func TestSetHostTemplate(t *testing.T) {
+	w := httptest.NewRecorder()
+	cookie := sookie.HostTemplate("session")
+	ensure.DeepEqual(t, cookie.Name, "__Host-session")
+	err := sookie.Set(secret, w, given, cookie)
+	ensure.Nil(t, err)
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Cookie", w.Header().Get("Set-Cookie"))
+	actual, err := sookie.Get[Flash](secret, r, "__Host-session")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, actual, given)
+}
+
+func TestHostTemplateKeepsPrefix(t *testing.T) {
+	ensure.DeepEqual(t, sookie.HostTemplate("__Host-session").Name, "__Host-session")
+}
+
+func TestSetErrorWithInvalidPrefix(t *testing.T) {
+	cases := []struct {
+		Cookie http.Cookie
+		Error  string
+	}{
+		{
+			Cookie: http.Cookie{Name: "__Host-a", Path: "/"},
+			Error:  "__Host- cookie must be Secure",
+		},
+		{
+			Cookie: http.Cookie{Name: "__Host-a", Secure: true},
+			Error:  "__Host- cookie must have Path set to /",
+		},
+		{
+			Cookie: http.Cookie{Name: "__Host-a", Secure: true, Path: "/", Domain: "example.com"},
+			Error:  "__Host- cookie must not have a Domain",
+		},
+		{
+			Cookie: http.Cookie{Name: "__Secure-a"},
+			Error:  "__Secure- cookie must be Secure",
+		},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		err := sookie.Set(secret, w, given, c.Cookie)
+		ensure.NotNil(t, err)
+		ensure.StringContains(t, err.Error(), c.Error)
+		ensure.True(t, errors.Is(err, sookie.ErrInvalidCookie))
+		ensure.DeepEqual(t, len(w.Header().Values("Set-Cookie")), 0)
+	}
+}
+
+func TestGetAnySkipsInvalid(t *testing.T) {
+	w := httptest.NewRecorder()
+	err := sookie.Set(secret, w, given, http.Cookie{Name: cookieName})
+	ensure.Nil(t, err)
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Cookie", cookieName+"=invalid; "+w.Header().Get("Set-Cookie"))
+	_, err = sookie.Get[Flash](secret, r, cookieName)
+	ensure.NotNil(t, err)
+	actual, err := sookie.GetAny[Flash](secret, r, cookieName)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, actual, given)
+}
+
+func TestGetAnyAllInvalid(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Cookie", cookieName+"=invalid; "+cookieName+"=@")
+	_, err := sookie.GetAny[Flash](secret, r, cookieName)
+	ensure.NotNil(t, err)
+	ensure.StringContains(t, err.Error(), "sookie: invalid cookie length")
+	ensure.True(t, errors.Is(err, sookie.ErrInvalidLength))
+}
+
+func TestGetAnyNoCookie(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	_, err := sookie.GetAny[Flash](secret, r, cookieName)
+	ensure.DeepEqual(t, err, http.ErrNoCookie)
+}
+
+func TestRewrap(t *testing.T) {
+	newSecret := bytes.Repeat([]byte("b"), len(secret))
+	expires := time.Now().Add(time.Hour)
+	raw, err := sookie.Seal(secret, expires, given)
+	ensure.Nil(t, err)
+	rewrapped, err := sookie.Rewrap(secret, newSecret, raw)
+	ensure.Nil(t, err)
+	_, err = sookie.Open[Flash](secret, rewrapped)
+	ensure.NotNil(t, err)
+	actual, err := sookie.Open[Flash](newSecret, rewrapped)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, actual, given)
+}
+
+func TestRewrapExpired(t *testing.T) {
+	newSecret := bytes.Repeat([]byte("b"), len(secret))
+	raw, err := sookie.Seal(secret, time.Now().Add(-time.Hour), given)
+	ensure.Nil(t, err)
+	_, err = sookie.Rewrap(secret, newSecret, raw)
+	ensure.DeepEqual(t, err, sookie.ErrExpired)
+}
+
+func TestRewrapMarshalerMismatch(t *testing.T) {
+	from, err := sookie.New(secret, sookie.WithMarshaler(sookie.Gob))
+	ensure.Nil(t, err)
+	to, err := sookie.New(bytes.Repeat([]byte("n"), 32))
+	ensure.Nil(t, err)
+	raw, err := from.Seal(time.Time{}, given)
+	ensure.Nil(t, err)
+	_, err = to.Rewrap(from, raw)
+	ensure.True(t, errors.Is(err, sookie.ErrFormat))
+}
+
+func TestRewrapSecretMismatch(t *testing.T) {
+	newSecret := bytes.Repeat([]byte("b"), len(secret))
+	raw, err := sookie.Seal(secret, time.Time{}, given)
+	ensure.Nil(t, err)
+	_, err = sookie.Rewrap(newSecret, secret, raw)
+	ensure.NotNil(t, err)
+	ensure.StringContains(t, err.Error(), "sookie: failed to decrypt cookie")
+}
+
+func TestSealWithStats(t *testing.T) {
+	raw, stats, err := sookie.SealWithStats(secret, time.Time{}, given)
+	ensure.Nil(t, err)
+	ensure.True(t, stats.Marshaled > 0)
+	ensure.True(t, stats.Compressed > 0)
+	ensure.DeepEqual(t, stats.Encoded, len(raw))
+	actual, err := sookie.Open[Flash](secret, raw)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, actual, given)
+}
+
+func TestSealNoCompress(t *testing.T) {
+	value := strings.Repeat("compressible ", 100)
+	raw, err := sookie.SealNoCompress(secret, time.Time{}, value)
+	ensure.Nil(t, err)
+	message, err := base64.RawURLEncoding.DecodeString(raw)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, message[2], byte(0))
+	compressed, err := sookie.Seal(secret, time.Time{}, value)
+	ensure.Nil(t, err)
+	ensure.True(t, len(compressed) < len(raw))
+	actual, err := sookie.Open[string](secret, raw)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, actual, value)
+}
+
+func TestCanSeal(t *testing.T) {
+	ensure.Nil(t, sookie.CanSeal(given))
+	err := sookie.CanSeal(struct{ P uintptr }{})
+	ensure.True(t, errors.Is(err, sookie.ErrMarshal))
+}
+
+func TestSealCookie(t *testing.T) {
+	header, err := sookie.SealCookie(secret, given, http.Cookie{Name: cookieName, Path: "/", MaxAge: 60})
+	ensure.Nil(t, err)
+	cookie, err := http.ParseSetCookie(header)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, cookie.Name, cookieName)
+	ensure.DeepEqual(t, cookie.Path, "/")
+	ensure.DeepEqual(t, cookie.MaxAge, 60)
+	actual, err := sookie.Open[Flash](secret, cookie.Value)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, actual, given)
+
+	_, err = sookie.SealCookie(secret, given, http.Cookie{Name: cookieName, Value: "x"})
+	ensure.True(t, errors.Is(err, sookie.ErrValueMustBeEmpty))
+}
+
+func TestMakeCookie(t *testing.T) {
+	template := http.Cookie{Name: cookieName, Path: "/", MaxAge: 60}
+	cookie, err := sookie.MakeCookie(secret, given, template)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, cookie.Name, cookieName)
+	ensure.DeepEqual(t, cookie.Path, "/")
+	ensure.DeepEqual(t, cookie.MaxAge, 60)
+	ensure.DeepEqual(t, template.Value, "")
+	actual, err := sookie.Open[Flash](secret, cookie.Value)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, actual, given)
+
+	_, err = sookie.MakeCookie(secret, given, http.Cookie{Name: "__Host-x", Path: "/a"})
+	ensure.NotNil(t, err)
+}
+
+func TestValidateBatch(t *testing.T) {
+	valid, err := sookie.Seal(secret, time.Time{}, given)
+	ensure.Nil(t, err)
+	expired, err := sookie.Seal(secret, time.Now().Add(-time.Hour), given)
+	ensure.Nil(t, err)
+	other, err := sookie.Seal(bytes.Repeat([]byte("o"), 32), time.Time{}, given)
+	ensure.Nil(t, err)
+	errs := sookie.ValidateBatch(secret, []string{valid, expired, other})
+	ensure.DeepEqual(t, len(errs), 3)
+	ensure.Nil(t, errs[0])
+	ensure.True(t, errors.Is(errs[1], sookie.ErrExpired))
+	ensure.True(t, errors.Is(errs[2], sookie.ErrDecrypt))
+
+	errs = sookie.ValidateBatch([]byte("short"), []string{valid, expired})
+	ensure.True(t, errors.Is(errs[0], sookie.ErrAEAD))
+	ensure.True(t, errors.Is(errs[1], sookie.ErrAEAD))
+}
+
+func TestSealWithStatsDefaultWrapperSize(t *testing.T) {
+	now := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	c, err := sookie.New(secret, sookie.WithClock(func() time.Time { return now }))
+	ensure.Nil(t, err)
+	_, stats, err := c.SealWithStats(time.Time{}, given)
+	ensure.Nil(t, err)
+	// the wrapper only holds V, E and C, as the other fields are empty
+	ensure.DeepEqual(t, stats.Marshaled, 85)
+}
+
+func TestEstimateSize(t *testing.T) {
+	size, err := sookie.EstimateSize(secret, given)
+	ensure.Nil(t, err)
+	raw, err := sookie.Seal(secret, time.Now().AddDate(0, 0, 365), given)
+	ensure.Nil(t, err)
+	// the timestamps may compress slightly differently
+	ensure.True(t, size > len(raw)-16 && size < len(raw)+16)
+	ensure.True(t, size < 4096)
+}
+
+func TestOpenInto(t *testing.T) {
+	raw, err := sookie.Seal(secret, time.Time{}, given)
+	ensure.Nil(t, err)
+	var actual Flash
+	err = sookie.OpenInto(secret, raw, &actual)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, actual, given)
+}
+
+func TestOpenIntoExpired(t *testing.T) {
+	raw, err := sookie.Seal(secret, time.Now().Add(-time.Hour), given)
+	ensure.Nil(t, err)
+	var actual Flash
+	err = sookie.OpenInto(secret, raw, &actual)
+	ensure.DeepEqual(t, err, sookie.ErrExpired)
+	ensure.DeepEqual(t, actual, given)
+}
+
+func TestOpenIntoNonPointer(t *testing.T) {
+	raw, err := sookie.Seal(secret, time.Time{}, given)
+	ensure.Nil(t, err)
+	var actual Flash
+	err = sookie.OpenInto(secret, raw, actual)
+	ensure.NotNil(t, err)
+	ensure.StringContains(t, err.Error(), "sookie: destination must be a non-nil pointer")
+	err = sookie.OpenInto(secret, raw, (*Flash)(nil))
+	ensure.NotNil(t, err)
+	ensure.StringContains(t, err.Error(), "sookie: destination must be a non-nil pointer")
+}
+
+func TestValid(t *testing.T) {
+	raw, err := sookie.Seal(secret, time.Now().Add(time.Hour), given)
+	ensure.Nil(t, err)
+	ensure.Nil(t, sookie.Valid(secret, raw))
+}
+
+func TestValidExpired(t *testing.T) {
+	raw, err := sookie.Seal(secret, time.Now().Add(-time.Hour), given)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, sookie.Valid(secret, raw), sookie.ErrExpired)
+}
+
+func TestValidSecretMismatch(t *testing.T) {
+	raw, err := sookie.Seal(secret, time.Time{}, given)
+	ensure.Nil(t, err)
+	err = sookie.Valid(bytes.Repeat([]byte("a"), len(secret)), raw)
+	ensure.NotNil(t, err)
+	ensure.StringContains(t, err.Error(), "sookie: failed to decrypt cookie")
+}
+
+func TestHas(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	ensure.False(t, sookie.Has(secret, r, cookieName))
+
+	expired, err := sookie.Seal(secret, time.Now().Add(-time.Hour), given)
+	ensure.Nil(t, err)
+	r.AddCookie(&http.Cookie{Name: "expired", Value: expired})
+	ensure.False(t, sookie.Has(secret, r, "expired"))
+	r.AddCookie(&http.Cookie{Name: "garbage", Value: "garbage"})
+	ensure.False(t, sookie.Has(secret, r, "garbage"))
+
+	w := httptest.NewRecorder()
+	ensure.Nil(t, sookie.Set(secret, w, given, http.Cookie{Name: cookieName, MaxAge: 60}))
+	r.AddCookie(w.Result().Cookies()[0])
+	ensure.True(t, sookie.Has(secret, r, cookieName))
+}
+
+func TestTouchExists(t *testing.T) {
+	w := httptest.NewRecorder()
+	err := sookie.Set(secret, w, given, http.Cookie{Name: cookieName, Path: "/", MaxAge: 60})
+	ensure.Nil(t, err)
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Cookie", w.Header().Get("Set-Cookie"))
+	value, err := r.Cookie(cookieName)
+	ensure.Nil(t, err)
+
+	w = httptest.NewRecorder()
+	sookie.Touch(w, r, http.Cookie{Name: cookieName, Path: "/", MaxAge: 3600})
+	set := w.Header().Get("Set-Cookie")
+	ensure.StringContains(t, set, cookieName+"="+value.Value+";")
+	ensure.StringContains(t, set, "Max-Age=3600")
+}
+
+func TestTouchMissing(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/", nil)
+	sookie.Touch(w, r, http.Cookie{Name: cookieName, MaxAge: 3600})
+	ensure.DeepEqual(t, len(w.Header().Values("Set-Cookie")), 0)
+}
+
+func TestReissueAttributes(t *testing.T) {
+	w := httptest.NewRecorder()
+	err := sookie.Set(secret, w, given, http.Cookie{Name: cookieName, Path: "/", MaxAge: 60})
+	ensure.Nil(t, err)
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Cookie", w.Header().Get("Set-Cookie"))
+	value, err := r.Cookie(cookieName)
+	ensure.Nil(t, err)
+
+	w = httptest.NewRecorder()
+	err = sookie.ReissueAttributes(w, r, cookieName, http.Cookie{
+		Path: "/", MaxAge: 60, Secure: true, SameSite: http.SameSiteStrictMode,
+	})
+	ensure.Nil(t, err)
+	set := w.Header().Get("Set-Cookie")
+	ensure.StringContains(t, set, cookieName+"="+value.Value+";")
+	ensure.StringContains(t, set, "Secure")
+	ensure.StringContains(t, set, "SameSite=Strict")
+
+	w = httptest.NewRecorder()
+	err = sookie.ReissueAttributes(w, r, "missing", http.Cookie{Path: "/"})
+	ensure.True(t, errors.Is(err, http.ErrNoCookie))
+	ensure.DeepEqual(t, len(w.Header().Values("Set-Cookie")), 0)
+}
+
+func TestDelMatching(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Cookie", cookieName+"=value; __Host-s=value")
+
+	w := httptest.NewRecorder()
+	ensure.Nil(t, sookie.DelMatching(w, r, http.Cookie{Name: cookieName, Path: "/app", SameSite: http.SameSiteLaxMode}))
+	ensure.DeepEqual(t, w.Header().Values("Set-Cookie"), []string{
+		cookieName + "=; Path=/app; Max-Age=0; SameSite=Lax",
+	})
+
+	w = httptest.NewRecorder()
+	err := sookie.DelMatching(w, r, http.Cookie{Name: cookieName})
+	ensure.True(t, errors.Is(err, sookie.ErrInvalidCookie))
+	err = sookie.DelMatching(w, r, http.Cookie{Name: "__Host-s", Path: "/"})
+	ensure.True(t, errors.Is(err, sookie.ErrInvalidCookie))
+	ensure.DeepEqual(t, len(w.Header().Values("Set-Cookie")), 0)
+}
+
+func TestDelAll(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Cookie", cookieName+"=value")
+	sookie.DelAll(w, r,
+		http.Cookie{Name: cookieName, Path: "/"},
+		http.Cookie{Name: cookieName, Path: "/app"},
+		http.Cookie{Name: cookieName, Domain: "example.com"},
+		http.Cookie{Name: "missing", Path: "/"},
+	)
+	ensure.DeepEqual(t, w.Header().Values("Set-Cookie"), []string{
+		cookieName + "=; Path=/; Max-Age=0",
+		cookieName + "=; Path=/app; Max-Age=0",
+		cookieName + "=; Domain=example.com; Max-Age=0",
+	})
+}
+
+func TestSetWithSize(t *testing.T) {
+	w := httptest.NewRecorder()
+	size, err := sookie.SetWithSize(secret, w, given, http.Cookie{Name: cookieName, Path: "/"})
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, size, len(w.Header().Get("Set-Cookie")))
+}
+
+func TestSetWithSizeError(t *testing.T) {
+	w := httptest.NewRecorder()
+	size, err := sookie.SetWithSize(secret, w, given, http.Cookie{Name: cookieName, Value: "x"})
+	ensure.True(t, errors.Is(err, sookie.ErrValueMustBeEmpty))
+	ensure.DeepEqual(t, size, 0)
+}
+
+func TestOpenRaw(t *testing.T) {
+	expires := time.Now().Add(time.Hour).Truncate(time.Second)
+	raw, err := sookie.Seal(secret, expires, given)
+	ensure.Nil(t, err)
+	plaintext, actual, err := sookie.OpenRaw(secret, raw)
+	ensure.Nil(t, err)
+	ensure.True(t, actual.Equal(expires))
+	ensure.StringContains(t, string(plaintext), given.Kind)
+}
+
+func TestOpenRawWithoutExpiry(t *testing.T) {
+	raw, err := sookie.Seal(secret, time.Time{}, given)
+	ensure.Nil(t, err)
+	_, actual, err := sookie.OpenRaw(secret, raw)
+	ensure.Nil(t, err)
+	ensure.True(t, actual.IsZero())
+}
+
+func TestOpenRawExpired(t *testing.T) {
+	raw, err := sookie.Seal(secret, time.Now().Add(-time.Hour), given)
+	ensure.Nil(t, err)
+	plaintext, _, err := sookie.OpenRaw(secret, raw)
+	ensure.DeepEqual(t, err, sookie.ErrExpired)
+	ensure.True(t, len(plaintext) > 0)
+}
+
+func TestGetFromCookieJar(t *testing.T) {
+	w := httptest.NewRecorder()
+	ensure.Nil(t, sookie.Set(secret, w, given, http.Cookie{Name: cookieName, Path: "/"}))
+	jar, err := cookiejar.New(nil)
+	ensure.Nil(t, err)
+	u, err := url.Parse("https://example.com/")
+	ensure.Nil(t, err)
+	jar.SetCookies(u, w.Result().Cookies())
+	actual, err := sookie.GetFromCookies[Flash](secret, jar.Cookies(u), cookieName)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, actual, given)
+}
+
+func TestGetFromCookiesNoCookie(t *testing.T) {
+	_, err := sookie.GetFromCookies[Flash](secret, []*http.Cookie{{Name: "other"}}, cookieName)
+	ensure.DeepEqual(t, err, http.ErrNoCookie)
+}
+
+func TestList(t *testing.T) {
+	sealed, err := sookie.Seal(secret, time.Time{}, given)
+	ensure.Nil(t, err)
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(&http.Cookie{Name: "theme", Value: "dark"})
+	r.AddCookie(&http.Cookie{Name: cookieName, Value: sealed})
+	r.AddCookie(&http.Cookie{Name: "other", Value: sealed})
+	r.AddCookie(&http.Cookie{Name: cookieName, Value: sealed})
+	r.AddCookie(&http.Cookie{Name: "long", Value: "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"})
+	ensure.DeepEqual(t, sookie.List(r), []string{cookieName, "other"})
+}
+
+func TestCodecList(t *testing.T) {
+	c, err := sookie.New(secret,
+		sookie.WithValuePrefix("v1."),
+		sookie.WithEncoding(base64.URLEncoding),
+		sookie.WithSignOnly(true))
+	ensure.Nil(t, err)
+	sealed, err := c.Seal(time.Time{}, given)
+	ensure.Nil(t, err)
+	plain, err := sookie.Seal(secret, time.Time{}, given)
+	ensure.Nil(t, err)
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(&http.Cookie{Name: cookieName, Value: sealed})
+	r.AddCookie(&http.Cookie{Name: "plain", Value: plain})
+	r.AddCookie(&http.Cookie{Name: "theme", Value: "v1.dark"})
+	ensure.DeepEqual(t, c.List(r), []string{cookieName})
+	ensure.DeepEqual(t, sookie.List(r), []string{"plain"})
+}
+
+func TestListEmpty(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	ensure.True(t, sookie.List(r) == nil)
+}
+
+func TestDelReplacesSet(t *testing.T) {
+	w := httptest.NewRecorder()
+	cookie := http.Cookie{Name: cookieName, Path: "/"}
+	ensure.Nil(t, sookie.Set(secret, w, given, cookie))
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Cookie", cookieName+"=value")
+	sookie.Del(w, r, cookie)
+	ensure.DeepEqual(t, w.Header().Values("Set-Cookie"), []string{
+		cookieName + "=; Path=/; Max-Age=0",
+	})
+}
+
+func TestDelReplacesSetWithoutRequestCookie(t *testing.T) {
+	w := httptest.NewRecorder()
+	cookie := http.Cookie{Name: cookieName, Path: "/"}
+	ensure.Nil(t, sookie.Set(secret, w, given, cookie))
+	ensure.Nil(t, sookie.Set(secret, w, given, http.Cookie{Name: cookieName, Path: "/app"}))
+	sookie.Del(w, httptest.NewRequest("GET", "/", nil), cookie)
+	values := w.Header().Values("Set-Cookie")
+	ensure.DeepEqual(t, len(values), 2)
+	ensure.DeepEqual(t, values[0], cookieName+"=; Path=/; Max-Age=0")
+	ensure.True(t, strings.HasSuffix(values[1], "; Path=/app"))
+
+	w = httptest.NewRecorder()
+	sookie.Del(w, httptest.NewRequest("GET", "/", nil), cookie)
+	ensure.DeepEqual(t, len(w.Header().Values("Set-Cookie")), 0)
+}
+
+func TestSetReplacesSet(t *testing.T) {
+	w := httptest.NewRecorder()
+	ensure.Nil(t, sookie.Set(secret, w, "first", http.Cookie{Name: cookieName}))
+	ensure.Nil(t, sookie.Set(secret, w, "other", http.Cookie{Name: cookieName, Path: "/app"}))
+	ensure.Nil(t, sookie.Set(secret, w, "second", http.Cookie{Name: cookieName}))
+	ensure.DeepEqual(t, len(w.Header().Values("Set-Cookie")), 2)
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Cookie", w.Header().Values("Set-Cookie")[0])
+	actual, err := sookie.Get[string](secret, r, cookieName)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, actual, "second")
+}
+
+func TestValidateSecrets(t *testing.T) {
+	other := bytes.Repeat([]byte("b"), 32)
+	ensure.Nil(t, sookie.ValidateSecrets([][]byte{secret, other}))
+}
+
+func TestValidateSecretsErrors(t *testing.T) {
+	err := sookie.ValidateSecrets([][]byte{secret, []byte("short"), secret})
+	ensure.StringContains(t, err.Error(), "secret 1 must be 32 bytes, got 5")
+	ensure.StringContains(t, err.Error(), "secret 2 is a duplicate of secret 0")
+}
+
+func TestValidateSecretsEmpty(t *testing.T) {
+	ensure.NotNil(t, sookie.ValidateSecrets(nil))
+}
+
+func TestGetAndDelete(t *testing.T) {
+	w := httptest.NewRecorder()
+	cookie := http.Cookie{Name: cookieName, Path: "/"}
+	ensure.Nil(t, sookie.Set(secret, w, given, cookie))
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Cookie", w.Header().Get("Set-Cookie"))
+	w = httptest.NewRecorder()
+	actual, err := sookie.GetAndDelete[Flash](secret, w, r, cookie)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, actual, given)
+	ensure.DeepEqual(t, w.Header().Get("Set-Cookie"), cookieName+"=; Path=/; Max-Age=0")
+}
+
+func TestGetAndDeleteInvalid(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Cookie", cookieName+"=invalid")
+	_, err := sookie.GetAndDelete[Flash](secret, w, r, http.Cookie{Name: cookieName})
+	ensure.NotNil(t, err)
+	ensure.DeepEqual(t, w.Header().Get("Set-Cookie"), cookieName+"=; Max-Age=0")
+}
+
+func TestGetAndDeleteNoCookie(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/", nil)
+	_, err := sookie.GetAndDelete[Flash](secret, w, r, http.Cookie{Name: cookieName})
+	ensure.True(t, errors.Is(err, http.ErrNoCookie))
+	ensure.DeepEqual(t, len(w.Header().Values("Set-Cookie")), 0)
+}
+
+func TestSetUntil(t *testing.T) {
+	expires := time.Now().Add(time.Hour).Truncate(time.Second)
+	w := httptest.NewRecorder()
+	ensure.Nil(t, sookie.SetUntil(secret, w, given, expires, http.Cookie{Name: cookieName}))
+	set := w.Header().Get("Set-Cookie")
+	ensure.True(t, strings.Contains(set, "Max-Age=3599") || strings.Contains(set, "Max-Age=3600"))
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Cookie", set)
+	cookie, err := r.Cookie(cookieName)
+	ensure.Nil(t, err)
+	_, actual, err := sookie.OpenRaw(secret, cookie.Value)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, actual.Unix(), expires.Unix())
+}
+
+func TestSetUntilExpired(t *testing.T) {
+	w := httptest.NewRecorder()
+	err := sookie.SetUntil(secret, w, given, time.Now().Add(-time.Minute), http.Cookie{Name: cookieName})
+	ensure.True(t, errors.Is(err, sookie.ErrExpired))
+	ensure.DeepEqual(t, len(w.Header().Values("Set-Cookie")), 0)
+}
+
+func TestMaxAge(t *testing.T) {
+	ensure.DeepEqual(t, sookie.MaxAge(90*time.Second+999*time.Millisecond), 90)
+}
+
+func TestSecureEqual(t *testing.T) {
+	sealed, err := sookie.Seal(secret, time.Time{}, given)
+	ensure.Nil(t, err)
+	ensure.True(t, sookie.SecureEqual(sealed, sealed))
+	altered := []byte(sealed)
+	altered[len(altered)-1] ^= 1
+	ensure.False(t, sookie.SecureEqual(sealed, string(altered)))
+	ensure.False(t, sookie.SecureEqual(sealed, sealed[1:]))
+	ensure.True(t, sookie.SecureEqual("", ""))
+}
+
+func TestOpenWithMetadata(t *testing.T) {
+	before := time.Now().Truncate(time.Second)
+	expires := time.Now().Add(time.Hour)
+	sealed, err := sookie.Seal(secret, expires, given)
+	ensure.Nil(t, err)
+	actual, md, err := sookie.OpenWithMetadata[Flash](secret, sealed)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, actual, given)
+	ensure.True(t, !md.IssuedAt.Before(before))
+	ensure.True(t, !md.IssuedAt.After(time.Now()))
+	ensure.DeepEqual(t, md.Expiry.Unix(), expires.Unix())
+}
+
+func TestGetWithMetadataPermanent(t *testing.T) {
+	w := httptest.NewRecorder()
+	ensure.Nil(t, sookie.Set(secret, w, given, http.Cookie{Name: cookieName}))
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Cookie", w.Header().Get("Set-Cookie"))
+	actual, md, err := sookie.GetWithMetadata[Flash](secret, r, cookieName)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, actual, given)
+	ensure.False(t, md.IssuedAt.IsZero())
+	ensure.True(t, md.Expiry.IsZero())
+}
+
+func roundTrip[V any](t *testing.T, value V) V {
+	t.Helper()
+	w := httptest.NewRecorder()
+	ensure.Nil(t, sookie.Set(secret, w, value, http.Cookie{Name: cookieName}))
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Cookie", w.Header().Get("Set-Cookie"))
+	actual, err := sookie.Get[V](secret, r, cookieName)
+	ensure.Nil(t, err)
+	return actual
+}
+
+func TestSliceValue(t *testing.T) {
+	ensure.DeepEqual(t, roundTrip(t, []int{3, 1, 2}), []int{3, 1, 2})
+}
+
+func TestArrayValue(t *testing.T) {
+	ensure.DeepEqual(t, roundTrip(t, [3]int{3, 1, 2}), [3]int{3, 1, 2})
+}
+
+func TestMapValue(t *testing.T) {
+	ensure.DeepEqual(t, roundTrip(t, map[string]int{"a": 1, "b": 2}), map[string]int{"a": 1, "b": 2})
+}
+
+func TestNilAndEmptySlice(t *testing.T) {
+	empty := roundTrip(t, []int{})
+	ensure.True(t, empty != nil)
+	ensure.DeepEqual(t, len(empty), 0)
+	ensure.True(t, roundTrip(t, []int(nil)) == nil)
+}
+
+func TestNilAndEmptyMap(t *testing.T) {
+	empty := roundTrip(t, map[string]int{})
+	ensure.True(t, empty != nil)
+	ensure.DeepEqual(t, len(empty), 0)
+	ensure.True(t, roundTrip(t, map[string]int(nil)) == nil)
+}
+
+func TestOpenTruncated(t *testing.T) {
+	raw, err := sookie.Seal(secret, time.Time{}, given)
+	ensure.Nil(t, err)
+
+	// too short to be a sealed value
+	_, err = sookie.Open[Flash](secret, raw[:40])
+	ensure.True(t, errors.Is(err, sookie.ErrTruncated))
+	ensure.True(t, errors.Is(err, sookie.ErrInvalidLength))
+
+	// the encoding ends part way through a byte
+	cut := raw[:len(raw)-1]
+	for len(cut)%4 != 1 {
+		cut = cut[:len(cut)-1]
+	}
+	_, err = sookie.Open[Flash](secret, cut)
+	ensure.True(t, errors.Is(err, sookie.ErrTruncated))
+	ensure.True(t, errors.Is(err, sookie.ErrDecode))
+
+	// tampering is not reported as truncation
+	tampered := []byte(raw)
+	tampered[len(tampered)/2] ^= 1
+	_, err = sookie.Open[Flash](secret, string(tampered))
+	ensure.False(t, errors.Is(err, sookie.ErrTruncated))
+}
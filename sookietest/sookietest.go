@@ -0,0 +1,85 @@
+// Package sookietest provides helpers for testing handlers which set cookies
+// using sookie.
+package sookietest
+
+import (
+	"encoding/binary"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/daaku/sookie"
+)
+
+// CookieOf returns the cookie with the given name set on the recorded
+// response, failing the test if there is none.
+func CookieOf(t testing.TB, w *httptest.ResponseRecorder, name string) *http.Cookie {
+	t.Helper()
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+	t.Fatalf("sookietest: no cookie named %q was set", name)
+	return nil
+}
+
+// ValueOf opens the cookie with the given name set on the recorded response,
+// failing the test if there is none, or if it can not be opened using the
+// secret.
+func ValueOf[V any](t testing.TB, secret []byte, w *httptest.ResponseRecorder, name string) V {
+	t.Helper()
+	cookie := CookieOf(t, w, name)
+	v, err := sookie.Open[V](secret, cookie.Value)
+	if err != nil {
+		t.Fatalf("sookietest: failed to open cookie %q: %v", name, err)
+	}
+	return v
+}
+
+// Forward adds the cookies set on the recorded response to the request, so
+// they are sent with a following request like a browser would. Cookies being
+// deleted are not added.
+func Forward(w *httptest.ResponseRecorder, r *http.Request) {
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.MaxAge >= 0 && cookie.Value != "" {
+			r.AddCookie(&http.Cookie{Name: cookie.Name, Value: cookie.Value})
+		}
+	}
+}
+
+// RandReader returns a reader of deterministic bytes generated from the seed,
+// for use with sookie.WithRandReader in golden tests. Unlike the fixed nonce
+// of DeterministicCodec, each value sealed by a Codec gets a different nonce,
+// but the same sequence of values seals to the same raw values every time.
+// It is not cryptographically random, so it must never be used outside of
+// tests, and is not safe for concurrent use.
+func RandReader(seed uint64) io.Reader {
+	var s [32]byte
+	binary.LittleEndian.PutUint64(s[:], seed)
+	return rand.NewChaCha8(s)
+}
+
+// Now is the fixed time used by DeterministicCodec.
+var Now = time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// DeterministicCodec returns a Codec which seals the same value into the same
+// raw value every time, allowing exact comparisons in golden tests. It reuses
+// an all zero nonce, and its clock is fixed at Now, which is stored as the
+// creation time of values and used to compute the expiry of cookies set with
+// a MaxAge. Reusing a nonce completely breaks the encryption, so it must
+// never be used outside of tests. The options are applied after the ones
+// making it deterministic, and must not add randomness, such as expiry jitter
+// or single use values.
+func DeterministicCodec(secret []byte, options ...sookie.Option) (*sookie.Codec, error) {
+	return sookie.New(secret, append([]sookie.Option{
+		sookie.WithNonceSource(func(nonce []byte) error {
+			clear(nonce)
+			return nil
+		}),
+		sookie.WithClock(func() time.Time { return Now }),
+	}, options...)...)
+}
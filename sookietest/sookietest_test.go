@@ -0,0 +1,66 @@
+package sookietest_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/daaku/ensure"
+	"github.com/daaku/sookie"
+	"github.com/daaku/sookie/sookietest"
+)
+
+var secret = []byte("274521B016094DBAB7093B257545A96E")
+
+type Flash struct {
+	Kind    string
+	Content string
+}
+
+func TestValueOf(t *testing.T) {
+	given := Flash{Kind: "info", Content: "hello"}
+	w := httptest.NewRecorder()
+	ensure.Nil(t, sookie.Set(secret, w, given, http.Cookie{Name: "flash"}))
+	ensure.DeepEqual(t, sookietest.ValueOf[Flash](t, secret, w, "flash"), given)
+	ensure.DeepEqual(t, sookietest.CookieOf(t, w, "flash").Name, "flash")
+
+	r := httptest.NewRequest("GET", "/", nil)
+	sookietest.Forward(w, r)
+	actual, err := sookie.Get[Flash](secret, r, "flash")
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, actual, given)
+}
+
+func TestDeterministicCodec(t *testing.T) {
+	given := Flash{Kind: "info", Content: "hello"}
+	c, err := sookietest.DeterministicCodec(secret)
+	ensure.Nil(t, err)
+	w1 := httptest.NewRecorder()
+	ensure.Nil(t, c.Set(w1, given, http.Cookie{Name: "flash", MaxAge: 60}))
+	w2 := httptest.NewRecorder()
+	ensure.Nil(t, c.Set(w2, given, http.Cookie{Name: "flash", MaxAge: 60}))
+	ensure.DeepEqual(t, w1.Header().Get("Set-Cookie"), w2.Header().Get("Set-Cookie"))
+
+	var actual Flash
+	ensure.Nil(t, c.Open(sookietest.CookieOf(t, w1, "flash").Value, &actual))
+	ensure.DeepEqual(t, actual, given)
+}
+
+func TestRandReader(t *testing.T) {
+	seal := func() []string {
+		c, err := sookie.New(secret, sookie.WithRandReader(sookietest.RandReader(1)),
+			sookie.WithClock(func() time.Time { return sookietest.Now }))
+		ensure.Nil(t, err)
+		var raws []string
+		for range 2 {
+			raw, err := c.Seal(time.Time{}, "hello")
+			ensure.Nil(t, err)
+			raws = append(raws, raw)
+		}
+		return raws
+	}
+	first := seal()
+	ensure.DeepEqual(t, seal(), first)
+	ensure.True(t, first[0] != first[1])
+}
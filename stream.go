@@ -0,0 +1,90 @@
+package sookie
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// frameHeaderSize is the size of the big-endian length prefix written before
+// each sealed message by SealTo.
+const frameHeaderSize = 4
+
+// SealTo is like Seal, but writes the sealed value to w instead of returning
+// a base64 encoded string. The raw header, nonce and ciphertext are written as
+// a single frame, prefixed by their length as a 4 byte big-endian integer, allowing
+// several values to be written to the same stream and read back with OpenFrom.
+func SealTo[V any](secret []byte, expires time.Time, value V, w io.Writer) error {
+	c, err := New(secret)
+	if err != nil {
+		return err
+	}
+	return c.SealTo(expires, value, w)
+}
+
+// OpenFrom reads a single frame written by SealTo from r and opens it.
+// If the value is expired, the ErrExpired error is returned.
+func OpenFrom[V any](secret []byte, r io.Reader) (V, error) {
+	var v V
+	c, err := New(secret)
+	if err != nil {
+		return v, err
+	}
+	err = c.OpenFrom(r, &v)
+	return v, err
+}
+
+// SealTo writes a single sealed frame to w like the package level SealTo function.
+func (c *Codec) SealTo(expires time.Time, value any, w io.Writer) error {
+	msgp, err := c.marshal(c.sealExpiry(expires), value)
+	if err != nil {
+		return err
+	}
+	buf := getBuf()
+	defer putBuf(buf)
+	frame := append(*buf, make([]byte, frameHeaderSize)...)
+	frame, err = c.sealBytes(frame, msgp, nil)
+	if err != nil {
+		return err
+	}
+	*buf = frame
+	size := len(frame) - frameHeaderSize
+	if size > math.MaxUint32 {
+		return errors.New("sookie: sealed value too large for frame")
+	}
+	binary.BigEndian.PutUint32(frame, uint32(size))
+	if _, err := w.Write(frame); err != nil {
+		return fmt.Errorf("sookie: failed to write frame: %w", err)
+	}
+	return nil
+}
+
+// OpenFrom reads a single frame from r and unmarshals it into the value
+// pointed to by dst, like the package level OpenFrom function.
+func (c *Codec) OpenFrom(r io.Reader, dst any) error {
+	if err := checkDst(dst); err != nil {
+		return err
+	}
+	var header [frameHeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return fmt.Errorf("sookie: failed to read frame: %w", err)
+	}
+	size := int64(binary.BigEndian.Uint32(header[:]))
+	// read incrementally to avoid trusting the length prefix for the allocation
+	message, err := io.ReadAll(io.LimitReader(r, size))
+	if err != nil {
+		return fmt.Errorf("sookie: failed to read frame: %w", err)
+	}
+	if int64(len(message)) != size {
+		return fmt.Errorf("sookie: failed to read frame: %w", io.ErrUnexpectedEOF)
+	}
+	uncompressed, err := c.openBytes(message)
+	if err != nil {
+		return err
+	}
+	_, err = c.unmarshalInto(uncompressed, dst)
+	return err
+}
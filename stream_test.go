@@ -0,0 +1,51 @@
+package sookie_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/daaku/ensure"
+	"github.com/daaku/sookie"
+)
+
+func TestSealToOpenFrom(t *testing.T) {
+	var buf bytes.Buffer
+	ensure.Nil(t, sookie.SealTo(secret, time.Time{}, given, &buf))
+	other := Flash{Kind: "alert-danger", Content: "second"}
+	ensure.Nil(t, sookie.SealTo(secret, time.Time{}, other, &buf))
+	actual, err := sookie.OpenFrom[Flash](secret, &buf)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, actual, given)
+	actual, err = sookie.OpenFrom[Flash](secret, &buf)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, actual, other)
+	_, err = sookie.OpenFrom[Flash](secret, &buf)
+	ensure.NotNil(t, err)
+	ensure.StringContains(t, err.Error(), "sookie: failed to read frame")
+}
+
+func TestOpenFromExpired(t *testing.T) {
+	var buf bytes.Buffer
+	ensure.Nil(t, sookie.SealTo(secret, time.Now().Add(-time.Hour), given, &buf))
+	_, err := sookie.OpenFrom[Flash](secret, &buf)
+	ensure.DeepEqual(t, err, sookie.ErrExpired)
+}
+
+func TestOpenFromTruncated(t *testing.T) {
+	var buf bytes.Buffer
+	ensure.Nil(t, sookie.SealTo(secret, time.Time{}, given, &buf))
+	truncated := bytes.NewReader(buf.Bytes()[:buf.Len()-1])
+	_, err := sookie.OpenFrom[Flash](secret, truncated)
+	ensure.NotNil(t, err)
+	ensure.StringContains(t, err.Error(), io.ErrUnexpectedEOF.Error())
+}
+
+func TestSealToErrorWithUnsupportedMarshal(t *testing.T) {
+	var buf bytes.Buffer
+	err := sookie.SealTo(secret, time.Time{}, struct{ P uintptr }{}, &buf)
+	ensure.NotNil(t, err)
+	ensure.StringContains(t, err.Error(), "sookie: failed to marshal value")
+	ensure.DeepEqual(t, buf.Len(), 0)
+}
@@ -0,0 +1,75 @@
+package sookie
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// WithStrictUnmarshal configures whether opening a struct value fails when
+// the sealed value has fields the struct does not. By default unmarshaling is
+// lenient: fields missing from the sealed value are left zero, and fields the
+// struct no longer has are ignored, so adding or removing fields does not
+// break values sealed before the change. Strict unmarshaling instead fails
+// with the ErrUnmarshal error on unknown fields, which helps catch version
+// drift between the code sealing and opening values. Only the top level
+// fields of the struct are checked. Strict unmarshaling needs a Marshaler
+// which encodes structs as maps, so it is not supported with Gob.
+func WithStrictUnmarshal(strict bool) Option {
+	return func(c *Codec) error {
+		c.strict = strict
+		return nil
+	}
+}
+
+// fieldTag is the struct tag used to name fields by the Marshaler with the
+// given ID, or empty if field names are used as is.
+func fieldTag(id byte) string {
+	switch id {
+	case msgPackID:
+		return "msgpack"
+	case cborID:
+		return "cbor"
+	case jsonID:
+		return "json"
+	}
+	return ""
+}
+
+// checkFields returns an error if the value in the marshaled wrapper has
+// fields which the struct type t does not.
+func (c *Codec) checkFields(uncompressed []byte, t reflect.Type) error {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	var w struct{ V map[string]any }
+	if err := c.marshaler.Unmarshal(uncompressed, &w); err != nil {
+		return c.unmarshalError(uncompressed, err)
+	}
+	known := map[string]bool{}
+	tag := fieldTag(c.marshaler.ID())
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name := f.Name
+		if tag != "" {
+			if v, _, _ := strings.Cut(f.Tag.Get(tag), ","); v == "-" {
+				continue
+			} else if v != "" {
+				name = v
+			}
+		}
+		known[name] = true
+	}
+	for name := range w.V {
+		if !known[name] {
+			return c.unmarshalError(uncompressed, fmt.Errorf("unknown field %q", name))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package sookie_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/daaku/ensure"
+	"github.com/daaku/sookie"
+)
+
+type sessionV1 struct {
+	User  string
+	Theme string
+}
+
+type sessionV2 struct {
+	User  string
+	Admin bool
+}
+
+func TestUnmarshalLenientAcrossVersions(t *testing.T) {
+	sealed, err := sookie.Seal(secret, time.Time{}, sessionV1{User: "alice", Theme: "dark"})
+	ensure.Nil(t, err)
+	actual, err := sookie.Open[sessionV2](secret, sealed)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, actual, sessionV2{User: "alice"})
+}
+
+func TestWithStrictUnmarshal(t *testing.T) {
+	c, err := sookie.New(secret, sookie.WithStrictUnmarshal(true))
+	ensure.Nil(t, err)
+	sealed, err := c.Seal(time.Time{}, sessionV1{User: "alice", Theme: "dark"})
+	ensure.Nil(t, err)
+
+	var v1 sessionV1
+	ensure.Nil(t, c.Open(sealed, &v1))
+	ensure.DeepEqual(t, v1, sessionV1{User: "alice", Theme: "dark"})
+
+	var v2 sessionV2
+	err = c.Open(sealed, &v2)
+	ensure.True(t, errors.Is(err, sookie.ErrUnmarshal))
+	ensure.StringContains(t, err.Error(), `unknown field "Theme"`)
+	ensure.DeepEqual(t, v2, sessionV2{})
+}
+
+func TestWithStrictUnmarshalMissingField(t *testing.T) {
+	c, err := sookie.New(secret, sookie.WithStrictUnmarshal(true))
+	ensure.Nil(t, err)
+	sealed, err := c.Seal(time.Time{}, struct{ User string }{User: "alice"})
+	ensure.Nil(t, err)
+	var actual sessionV2
+	ensure.Nil(t, c.Open(sealed, &actual))
+	ensure.DeepEqual(t, actual, sessionV2{User: "alice"})
+}
+
+func TestWithStrictUnmarshalGob(t *testing.T) {
+	_, err := sookie.New(secret, sookie.WithMarshaler(sookie.Gob), sookie.WithStrictUnmarshal(true))
+	ensure.StringContains(t, err.Error(), "not supported with Gob")
+}
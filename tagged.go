@@ -0,0 +1,62 @@
+package sookie
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// SealTagged is like Seal, but also stores the tag, which identifies the type
+// of the value, so OpenTagged can choose the type to open it into. This allows
+// migrating between incompatible types, such as when splitting a session type
+// in two, by opening values of both the old and new types.
+func SealTagged[V any](secret []byte, expires time.Time, tag string, value V) (string, error) {
+	c, err := New(secret)
+	if err != nil {
+		return "", err
+	}
+	return c.SealTagged(expires, tag, value)
+}
+
+// OpenTagged opens the raw value into the destination for its tag, returning
+// the tag. The destinations are pointers keyed by tag, and values sealed
+// without a tag, such as using Seal, have the empty tag. If there is no
+// destination for the tag, the ErrUnknownTag error is returned.
+func OpenTagged(secret []byte, raw string, dsts map[string]any) (string, error) {
+	c, err := New(secret)
+	if err != nil {
+		return "", err
+	}
+	return c.OpenTagged(raw, dsts)
+}
+
+// SealTagged seals a tagged value like the package level SealTagged function.
+func (c *Codec) SealTagged(expires time.Time, tag string, value any) (string, error) {
+	tc := *c
+	tc.tag = tag
+	return tc.Seal(expires, value)
+}
+
+// OpenTagged opens a tagged value like the package level OpenTagged function.
+func (c *Codec) OpenTagged(raw string, dsts map[string]any) (tag string, err error) {
+	done := c.trace(context.Background(), "open")
+	defer func() { done(err) }()
+
+	uncompressed, err := c.open(raw)
+	if err != nil {
+		return "", c.report("", err)
+	}
+	m, err := c.expiry(uncompressed)
+	if err != nil {
+		return "", c.report("", err)
+	}
+	dst, ok := dsts[m.tag]
+	if !ok {
+		return m.tag, c.report("", fmt.Errorf("%w: %q", ErrUnknownTag, m.tag))
+	}
+	if err := checkDst(dst); err != nil {
+		return m.tag, err
+	}
+	_, err = c.unmarshalInto(uncompressed, dst)
+	return m.tag, c.report("", err)
+}
@@ -0,0 +1,45 @@
+package sookie_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/daaku/ensure"
+	"github.com/daaku/sookie"
+)
+
+type splitSession struct {
+	User  string
+	Roles []string
+}
+
+func TestOpenTagged(t *testing.T) {
+	old, err := sookie.Seal(secret, time.Time{}, given)
+	ensure.Nil(t, err)
+	value := splitSession{User: "alice", Roles: []string{"admin"}}
+	raw, err := sookie.SealTagged(secret, time.Time{}, "v2", value)
+	ensure.Nil(t, err)
+
+	var v1 Flash
+	var v2 splitSession
+	dsts := map[string]any{"": &v1, "v2": &v2}
+	tag, err := sookie.OpenTagged(secret, old, dsts)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, tag, "")
+	ensure.DeepEqual(t, v1, given)
+
+	tag, err = sookie.OpenTagged(secret, raw, dsts)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, tag, "v2")
+	ensure.DeepEqual(t, v2, value)
+}
+
+func TestOpenTaggedUnknown(t *testing.T) {
+	raw, err := sookie.SealTagged(secret, time.Time{}, "v3", given)
+	ensure.Nil(t, err)
+	var v1 Flash
+	tag, err := sookie.OpenTagged(secret, raw, map[string]any{"": &v1})
+	ensure.True(t, errors.Is(err, sookie.ErrUnknownTag))
+	ensure.DeepEqual(t, tag, "v3")
+}
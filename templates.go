@@ -0,0 +1,61 @@
+package sookie
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// Register registers the template used for the cookie with the given name by
+// SetNamed, GetNamed and DelNamed, which keeps the Path, MaxAge, SameSite and
+// other attributes of each cookie in one place instead of at every call site.
+// The template Name is replaced by name, and registering a name again replaces
+// its template. Templates are shared with Codecs created by WithSecret.
+func (c *Codec) Register(name string, template http.Cookie) {
+	template.Name = name
+	c.templates.Store(name, template)
+}
+
+// Template returns the template registered for the cookie with the given name.
+func (c *Codec) Template(name string) (http.Cookie, error) {
+	template, ok := c.templates.Load(name)
+	if !ok {
+		return http.Cookie{}, fmt.Errorf("%w: %q", ErrUnregistered, name)
+	}
+	return template.(http.Cookie), nil
+}
+
+// SetNamed sets the cookie with the given name like Set, using its registered
+// template.
+func (c *Codec) SetNamed(w http.ResponseWriter, name string, value any) error {
+	return c.SetNamedCtx(context.Background(), w, name, value)
+}
+
+// SetNamedCtx is like SetNamed, but uses the context like SetCtx.
+func (c *Codec) SetNamedCtx(ctx context.Context, w http.ResponseWriter, name string, value any) error {
+	cookie, err := c.Template(name)
+	if err != nil {
+		return err
+	}
+	return c.SetCtx(ctx, w, value, cookie)
+}
+
+// GetNamed retrieves the cookie with the given name like Get. It fails if no
+// template was registered for the name, which catches typos in cookie names.
+func (c *Codec) GetNamed(r *http.Request, name string, dst any) error {
+	if _, err := c.Template(name); err != nil {
+		return err
+	}
+	return c.Get(r, name, dst)
+}
+
+// DelNamed deletes the cookie with the given name like Del, using the Path and
+// Domain of its registered template.
+func (c *Codec) DelNamed(w http.ResponseWriter, r *http.Request, name string) error {
+	cookie, err := c.Template(name)
+	if err != nil {
+		return err
+	}
+	Del(w, r, cookie)
+	return nil
+}
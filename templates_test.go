@@ -0,0 +1,58 @@
+package sookie_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/daaku/ensure"
+	"github.com/daaku/sookie"
+)
+
+func TestRegisterSetGetNamed(t *testing.T) {
+	c, err := sookie.New(secret)
+	ensure.Nil(t, err)
+	c.Register(cookieName, http.Cookie{Path: "/app", MaxAge: 60, SameSite: http.SameSiteStrictMode})
+	w := httptest.NewRecorder()
+	ensure.Nil(t, c.SetNamed(w, cookieName, given))
+	header := w.Header().Get("Set-Cookie")
+	ensure.True(t, strings.HasPrefix(header, cookieName+"="))
+	ensure.StringContains(t, header, "Path=/app")
+	ensure.StringContains(t, header, "Max-Age=60")
+	ensure.StringContains(t, header, "SameSite=Strict")
+
+	r := httptest.NewRequest("GET", "/app", nil)
+	r.Header.Set("Cookie", header)
+	var actual Flash
+	ensure.Nil(t, c.GetNamed(r, cookieName, &actual))
+	ensure.DeepEqual(t, actual, given)
+
+	w = httptest.NewRecorder()
+	ensure.Nil(t, c.DelNamed(w, r, cookieName))
+	ensure.StringContains(t, w.Header().Get("Set-Cookie"), "Path=/app")
+}
+
+func TestUnregisteredName(t *testing.T) {
+	c, err := sookie.New(secret)
+	ensure.Nil(t, err)
+	w := httptest.NewRecorder()
+	ensure.True(t, errors.Is(c.SetNamed(w, "missing", given), sookie.ErrUnregistered))
+	r := httptest.NewRequest("GET", "/", nil)
+	var actual Flash
+	ensure.True(t, errors.Is(c.GetNamed(r, "missing", &actual), sookie.ErrUnregistered))
+	ensure.True(t, errors.Is(c.DelNamed(w, r, "missing"), sookie.ErrUnregistered))
+	ensure.DeepEqual(t, len(w.Header()), 0)
+}
+
+func TestRegisterSharedWithSecret(t *testing.T) {
+	c, err := sookie.New(secret)
+	ensure.Nil(t, err)
+	c.Register(cookieName, http.Cookie{Path: "/app"})
+	rotated, err := c.WithSecret(make([]byte, 32))
+	ensure.Nil(t, err)
+	template, err := rotated.Template(cookieName)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, template.Path, "/app")
+}
@@ -0,0 +1,52 @@
+package sookie
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// WithMaxTotalSize limits the total size of the cookies set on a response,
+// counting the name and value of each Set-Cookie header, which is roughly
+// what browsers send back in the Cookie header. Browsers and proxies commonly
+// limit the Cookie header to about 8KB, and silently drop cookies beyond it.
+// Setting a cookie which would take the response over the limit fails with
+// the ErrTotalSize error. The size is computed from the Set-Cookie headers of
+// the response, so it includes cookies set by other code, but not cookies the
+// browser already has from earlier responses.
+func WithMaxTotalSize(max int) Option {
+	return func(c *Codec) error {
+		if max <= 0 {
+			return errors.New("sookie: max total size must be positive")
+		}
+		c.maxTotalSize = max
+		return nil
+	}
+}
+
+// checkTotalSize returns an error if adding the cookie to the response would
+// take it over the total size limit. A cookie being replaced, or deleted, is
+// not counted.
+func (c *Codec) checkTotalSize(w http.ResponseWriter, cookie *http.Cookie) error {
+	if c.maxTotalSize == 0 || cookie.MaxAge < 0 {
+		return nil
+	}
+	total := cookieSize(cookie)
+	for _, header := range w.Header()["Set-Cookie"] {
+		existing, err := http.ParseSetCookie(header)
+		if err != nil || existing.MaxAge < 0 || sameCookie(header, cookie) {
+			continue
+		}
+		// the separator between cookies in the Cookie header
+		total += cookieSize(existing) + 2
+	}
+	if total > c.maxTotalSize {
+		return fmt.Errorf("%w: %d bytes exceeds %d", ErrTotalSize, total, c.maxTotalSize)
+	}
+	return nil
+}
+
+// cookieSize returns the size of the cookie in the Cookie header.
+func cookieSize(cookie *http.Cookie) int {
+	return len(cookie.Name) + 1 + len(cookie.Value)
+}
@@ -0,0 +1,32 @@
+package sookie_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/daaku/ensure"
+	"github.com/daaku/sookie"
+)
+
+func TestWithMaxTotalSize(t *testing.T) {
+	c, err := sookie.New(secret, sookie.WithMaxTotalSize(1000))
+	ensure.Nil(t, err)
+	w := httptest.NewRecorder()
+	ensure.Nil(t, c.Set(w, given, http.Cookie{Name: "a"}))
+	// replacing the same cookie does not count it twice
+	ensure.Nil(t, c.Set(w, given, http.Cookie{Name: "a"}))
+	ensure.DeepEqual(t, len(w.Header()["Set-Cookie"]), 1)
+
+	http.SetCookie(w, &http.Cookie{Name: "other", Value: strings.Repeat("x", 800)})
+	err = c.Set(w, given, http.Cookie{Name: "b"})
+	ensure.True(t, errors.Is(err, sookie.ErrTotalSize))
+	ensure.DeepEqual(t, len(w.Header()["Set-Cookie"]), 2)
+}
+
+func TestWithMaxTotalSizeInvalid(t *testing.T) {
+	_, err := sookie.New(secret, sookie.WithMaxTotalSize(0))
+	ensure.NotNil(t, err)
+}
@@ -0,0 +1,125 @@
+package sookie
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+)
+
+// Error codes written by WriteUnauthorized. They are part of the API of the
+// responses, and do not change.
+const (
+	// CodeNoCookie means the request had no cookie.
+	CodeNoCookie = "no_cookie"
+
+	// CodeExpired means the cookie has expired, has been idle for too long or
+	// was issued before the cutoff.
+	CodeExpired = "expired"
+
+	// CodeInvalid means the cookie was valid, but can no longer be used, such
+	// as a value of a type which changed since it was sealed.
+	CodeInvalid = "invalid_cookie"
+
+	// CodeTampered means the cookie failed authentication or could not be
+	// decoded, so it was altered, truncated, forged, or sealed with a secret
+	// which is no longer configured.
+	CodeTampered = "tampered_cookie"
+
+	// CodeReplayed means a single use cookie was used again.
+	CodeReplayed = "replayed_cookie"
+
+	// CodeInternal means the cookie could not be opened because of a server
+	// side problem, such as an invalid secret.
+	CodeInternal = "internal_error"
+)
+
+// ErrorResponse is the response written for an error by an
+// UnauthorizedWriter. The body is a JSON object with the Code in its "error"
+// field.
+type ErrorResponse struct {
+	Status int
+	Code   string
+
+	// Log reports whether the error should be logged, such as for a tampered
+	// cookie, which may be an attack, or a server side problem.
+	Log bool
+}
+
+// ErrorMapper maps an error returned when opening a cookie to the response
+// written for it.
+type ErrorMapper func(err error) ErrorResponse
+
+// DefaultErrorMapper maps the errors returned by sookie to a response. A
+// missing cookie, http.ErrNoCookie, maps to CodeNoCookie, and an expired one
+// to CodeExpired. Cookies failing authentication or decoding, or with
+// implausible times, see WithMaxLifetime, map to CodeTampered and are logged,
+// and replayed cookies map to CodeReplayed and are also logged. Authenticated
+// cookies which can not be used map to CodeInvalid. These all use the 401
+// status. Any other error maps to CodeInternal with the 500 status, and is
+// logged.
+func DefaultErrorMapper(err error) ErrorResponse {
+	switch {
+	case errors.Is(err, http.ErrNoCookie):
+		return ErrorResponse{Status: http.StatusUnauthorized, Code: CodeNoCookie}
+	case errors.Is(err, ErrExpired), errors.Is(err, ErrStale):
+		return ErrorResponse{Status: http.StatusUnauthorized, Code: CodeExpired}
+	case errors.Is(err, ErrReplayed):
+		return ErrorResponse{Status: http.StatusUnauthorized, Code: CodeReplayed, Log: true}
+	case errors.Is(err, ErrDecrypt), errors.Is(err, ErrOuterMAC), errors.Is(err, ErrDecode),
+		errors.Is(err, ErrInvalidLength), errors.Is(err, ErrFormat), errors.Is(err, ErrChunk),
+		errors.Is(err, ErrUnknownLabel), errors.Is(err, ErrUnknownKeyVersion),
+		errors.Is(err, ErrUnknownTag), errors.Is(err, ErrLifetime):
+		return ErrorResponse{Status: http.StatusUnauthorized, Code: CodeTampered, Log: true}
+	case errors.Is(err, ErrUnmarshal), errors.Is(err, ErrZeroValue),
+		errors.Is(err, ErrDecompress), errors.Is(err, ErrDecompressedSize):
+		return ErrorResponse{Status: http.StatusUnauthorized, Code: CodeInvalid}
+	default:
+		return ErrorResponse{Status: http.StatusInternalServerError, Code: CodeInternal, Log: true}
+	}
+}
+
+// UnauthorizedWriter writes JSON error responses for errors returned when
+// opening a cookie, for API servers. The zero value uses DefaultErrorMapper
+// and logs using the default slog.Logger.
+type UnauthorizedWriter struct {
+	// Map maps errors to responses, or DefaultErrorMapper if nil. It can wrap
+	// DefaultErrorMapper to change only some of the responses.
+	Map ErrorMapper
+
+	// Logger logs errors for which the response has Log set, or the default
+	// slog.Logger if nil.
+	Logger *slog.Logger
+}
+
+// WriteUnauthorized writes a JSON error response for an error returned when
+// opening a cookie, such as by Get, using the zero UnauthorizedWriter.
+func WriteUnauthorized(w http.ResponseWriter, err error) {
+	UnauthorizedWriter{}.Write(w, err)
+}
+
+// Write writes the JSON error response for the error, and logs it if
+// required.
+func (u UnauthorizedWriter) Write(w http.ResponseWriter, err error) {
+	m := u.Map
+	if m == nil {
+		m = DefaultErrorMapper
+	}
+	res := m(err)
+	if res.Log {
+		logger := u.Logger
+		if logger == nil {
+			logger = slog.Default()
+		}
+		logger.Warn("sookie: rejected cookie", "code", res.Code, "error", err)
+	}
+	body, _ := json.Marshal(struct {
+		Error string `json:"error"`
+	}{res.Code})
+	h := w.Header()
+	h.Set("Content-Type", "application/json; charset=utf-8")
+	h.Set("X-Content-Type-Options", "nosniff")
+	h.Set("Cache-Control", "no-store")
+	w.WriteHeader(res.Status)
+	w.Write(append(body, '\n'))
+}
@@ -0,0 +1,84 @@
+package sookie_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/daaku/ensure"
+	"github.com/daaku/sookie"
+)
+
+func TestWriteUnauthorized(t *testing.T) {
+	c, err := sookie.New(secret)
+	ensure.Nil(t, err)
+	expired, err := c.Seal(time.Now().Add(-time.Hour), "value")
+	ensure.Nil(t, err)
+	cases := []struct {
+		name   string
+		value  string
+		status int
+		body   string
+	}{
+		{"missing", "", http.StatusUnauthorized, `{"error":"no_cookie"}`},
+		{"expired", expired, http.StatusUnauthorized, `{"error":"expired"}`},
+		{"tampered", expired[:len(expired)-2] + "AA", http.StatusUnauthorized, `{"error":"tampered_cookie"}`},
+		{"garbage", "!!", http.StatusUnauthorized, `{"error":"tampered_cookie"}`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			if tc.value != "" {
+				r.AddCookie(&http.Cookie{Name: "s", Value: tc.value})
+			}
+			var v string
+			err := c.Get(r, "s", &v)
+			ensure.NotNil(t, err)
+			w := httptest.NewRecorder()
+			sookie.UnauthorizedWriter{Logger: slog.New(slog.DiscardHandler)}.Write(w, err)
+			ensure.DeepEqual(t, w.Code, tc.status)
+			ensure.DeepEqual(t, w.Body.String(), tc.body+"\n")
+			ensure.DeepEqual(t, w.Header().Get("Content-Type"), "application/json; charset=utf-8")
+		})
+	}
+}
+
+func TestWriteUnauthorizedLogs(t *testing.T) {
+	var logs bytes.Buffer
+	u := sookie.UnauthorizedWriter{Logger: slog.New(slog.NewTextHandler(&logs, nil))}
+	u.Write(httptest.NewRecorder(), http.ErrNoCookie)
+	ensure.DeepEqual(t, logs.String(), "")
+	u.Write(httptest.NewRecorder(), fmt.Errorf("%w: bad tag", sookie.ErrDecrypt))
+	ensure.StringContains(t, logs.String(), "code=tampered_cookie")
+}
+
+func TestWriteUnauthorizedInternal(t *testing.T) {
+	w := httptest.NewRecorder()
+	u := sookie.UnauthorizedWriter{Logger: slog.New(slog.DiscardHandler)}
+	u.Write(w, errors.New("boom"))
+	ensure.DeepEqual(t, w.Code, http.StatusInternalServerError)
+	ensure.DeepEqual(t, w.Body.String(), `{"error":"internal_error"}`+"\n")
+}
+
+func TestWriteUnauthorizedMap(t *testing.T) {
+	u := sookie.UnauthorizedWriter{Map: func(err error) sookie.ErrorResponse {
+		if errors.Is(err, sookie.ErrExpired) {
+			return sookie.ErrorResponse{Status: http.StatusForbidden, Code: "session_expired"}
+		}
+		return sookie.DefaultErrorMapper(err)
+	}}
+	w := httptest.NewRecorder()
+	u.Write(w, sookie.ErrExpired)
+	ensure.DeepEqual(t, w.Code, http.StatusForbidden)
+	ensure.DeepEqual(t, w.Body.String(), `{"error":"session_expired"}`+"\n")
+
+	w = httptest.NewRecorder()
+	u.Write(w, http.ErrNoCookie)
+	ensure.DeepEqual(t, w.Code, http.StatusUnauthorized)
+	ensure.DeepEqual(t, w.Body.String(), `{"error":"no_cookie"}`+"\n")
+}
@@ -0,0 +1,41 @@
+package sookie
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// maxURLDecodes is the most layers of percent-encoding removed from a value.
+const maxURLDecodes = 2
+
+// WithURLDecodeFirst configures whether percent-encoding is removed from raw
+// values before decoding them, for values mangled by a proxy which
+// percent-encodes cookies, such as "%3D" for "=", possibly twice, as "%253D".
+// Values are only unescaped if they contain a '%', which can not be used in
+// the value prefix or encoding along with this option, and '+' is kept as is,
+// since it is part of some encodings. It is off by default, since it accepts
+// values which were not sent as they were set, and could hide a client or
+// proxy corrupting them.
+func WithURLDecodeFirst(enabled bool) Option {
+	return func(c *Codec) error {
+		c.urlDecode = enabled
+		return nil
+	}
+}
+
+// urlDecode removes up to maxURLDecodes layers of percent-encoding from the
+// raw value.
+func urlDecode(raw string) (string, error) {
+	for range maxURLDecodes {
+		if !strings.Contains(raw, "%") {
+			break
+		}
+		unescaped, err := url.PathUnescape(raw)
+		if err != nil {
+			return "", fmt.Errorf("%w: %w", ErrDecode, err)
+		}
+		raw = unescaped
+	}
+	return raw, nil
+}
@@ -0,0 +1,55 @@
+package sookie_test
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daaku/ensure"
+	"github.com/daaku/sookie"
+)
+
+// percentEncode percent-encodes every byte of s, like a misbehaving proxy.
+func percentEncode(s string) string {
+	var b strings.Builder
+	for i := range len(s) {
+		fmt.Fprintf(&b, "%%%02X", s[i])
+	}
+	return b.String()
+}
+
+func TestWithURLDecodeFirst(t *testing.T) {
+	c, err := sookie.New(secret, sookie.WithEncoding(base64.URLEncoding), sookie.WithValuePrefix("v1."))
+	ensure.Nil(t, err)
+	raw, err := c.Seal(time.Now().Add(time.Hour), "value")
+	ensure.Nil(t, err)
+	once := percentEncode(raw)
+	twice := percentEncode(once)
+
+	var actual string
+	ensure.True(t, errors.Is(c.Open(once, &actual), sookie.ErrFormat))
+
+	d, err := sookie.New(secret, sookie.WithEncoding(base64.URLEncoding), sookie.WithValuePrefix("v1."),
+		sookie.WithURLDecodeFirst(true))
+	ensure.Nil(t, err)
+	for _, v := range []string{raw, once, twice} {
+		actual = ""
+		ensure.Nil(t, d.Open(v, &actual))
+		ensure.DeepEqual(t, actual, "value")
+	}
+	ensure.True(t, errors.Is(d.Open(raw+"%zz", &actual), sookie.ErrDecode))
+	ensure.True(t, errors.Is(d.Open(percentEncode(twice), &actual), sookie.ErrFormat))
+}
+
+func TestWithURLDecodeFirstPercent(t *testing.T) {
+	_, err := sookie.New(secret, sookie.WithValuePrefix("v%1."), sookie.WithURLDecodeFirst(true))
+	ensure.NotNil(t, err)
+	percent := base64.NewEncoding("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789%!").WithPadding(base64.NoPadding)
+	_, err = sookie.New(secret, sookie.WithEncoding(percent), sookie.WithURLDecodeFirst(true))
+	ensure.NotNil(t, err)
+	_, err = sookie.New(secret, sookie.WithEncoding(percent))
+	ensure.Nil(t, err)
+}
@@ -0,0 +1,138 @@
+package sookie
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// WithKeyVersion stores the version of the secret in cleartext in the header
+// of sealed values, so a VersionedKeyring can select the secret directly
+// instead of trying each one in turn. The version is authenticated along with
+// the header, and a Codec with this option rejects values sealed with a
+// different version with the ErrDecrypt error, without attempting to decrypt
+// them. Values sealed without a version can still be opened.
+func WithKeyVersion(version byte) Option {
+	return func(c *Codec) error {
+		c.versioned = true
+		c.keyVersion = version
+		return nil
+	}
+}
+
+// VersionedKeyring seals values using the current secret, and opens them
+// using the secret whose version is stored in their header, see
+// WithKeyVersion. Unlike a Keyring, opening a value costs a single decryption
+// attempt regardless of how many secrets there are, so forged values can not
+// be used to multiply the work done.
+// A VersionedKeyring is safe for concurrent use by multiple goroutines.
+type VersionedKeyring struct {
+	current byte
+	codecs  map[byte]*Codec
+}
+
+// NewVersionedKeyring creates a VersionedKeyring using the secrets keyed by
+// their versions, and the given options. Values are sealed using the secret
+// with the current version, which must be present.
+func NewVersionedKeyring(current byte, secrets map[byte][]byte, options ...Option) (*VersionedKeyring, error) {
+	if _, ok := secrets[current]; !ok {
+		return nil, fmt.Errorf("sookie: no secret with the current version %d", current)
+	}
+	k := &VersionedKeyring{current: current, codecs: make(map[byte]*Codec, len(secrets))}
+	for version, secret := range secrets {
+		c, err := New(secret, append(options[:len(options):len(options)], WithKeyVersion(version))...)
+		if err != nil {
+			return nil, fmt.Errorf("sookie: key version %d: %w", version, err)
+		}
+		k.codecs[version] = c
+	}
+	return k, nil
+}
+
+// Codec returns the Codec for the secret with the given version.
+func (k *VersionedKeyring) Codec(version byte) (*Codec, error) {
+	c, ok := k.codecs[version]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownKeyVersion, version)
+	}
+	return c, nil
+}
+
+// KeyVersion returns the key version stored in the header of the raw value,
+// without opening it. The ErrUnknownKeyVersion error is returned if the value
+// was sealed without one.
+func (c *Codec) KeyVersion(raw string) (byte, error) {
+	raw, err := c.cutPrefix(raw)
+	if err != nil {
+		return 0, err
+	}
+	// only the header is needed, so just the first two groups of characters
+	// are decoded, which need no padding
+	n := c.encoding.EncodedLen(6)
+	message, err := c.encoding.DecodeString(raw[:min(n, len(raw))])
+	if err != nil {
+		return 0, decodeError(raw, err)
+	}
+	if len(message) < headerSize {
+		return 0, errTooShort
+	}
+	h, _, _, err := parseHeader(message)
+	if err != nil {
+		return 0, err
+	}
+	if !h.versioned {
+		return 0, fmt.Errorf("%w: sealed without a key version", ErrUnknownKeyVersion)
+	}
+	return h.keyVersion, nil
+}
+
+// codecFor returns the Codec for the secret with the version stored in the
+// raw value.
+func (k *VersionedKeyring) codecFor(raw string) (*Codec, error) {
+	version, err := k.codecs[k.current].KeyVersion(raw)
+	if err != nil {
+		return nil, err
+	}
+	return k.Codec(version)
+}
+
+// Seal encodes a value like Codec.Seal, using the current secret.
+func (k *VersionedKeyring) Seal(expires time.Time, value any) (string, error) {
+	return k.codecs[k.current].Seal(expires, value)
+}
+
+// Set sets a cookie like Codec.Set, using the current secret.
+func (k *VersionedKeyring) Set(w http.ResponseWriter, value any, cookie http.Cookie) error {
+	return k.codecs[k.current].Set(w, value, cookie)
+}
+
+// Open unmarshals the raw value into the value pointed to by dst like
+// Codec.Open, using the secret with the version stored in the value.
+func (k *VersionedKeyring) Open(raw string, dst any) error {
+	return k.open("", raw, dst)
+}
+
+// open opens the raw value, bound to the cookie name if the options require
+// it.
+func (k *VersionedKeyring) open(name, raw string, dst any) error {
+	c, err := k.codecFor(raw)
+	if err != nil {
+		return err
+	}
+	_, err = c.forName(name).openInto(context.Background(), raw, dst)
+	return err
+}
+
+// Get retrieves a cookie like Codec.Get, using the secret with the version
+// stored in the value.
+func (k *VersionedKeyring) Get(r *http.Request, name string, dst any) error {
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		if err == http.ErrNoCookie {
+			return err
+		}
+		return fmt.Errorf("sookie: failed to get cookie: %w", err)
+	}
+	return k.open(name, cookie.Value, dst)
+}
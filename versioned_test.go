@@ -0,0 +1,71 @@
+package sookie_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/daaku/ensure"
+	"github.com/daaku/sookie"
+)
+
+func TestVersionedKeyringRotation(t *testing.T) {
+	old, err := sookie.NewVersionedKeyring(1, map[byte][]byte{1: secret})
+	ensure.Nil(t, err)
+	raw, err := old.Seal(time.Time{}, given)
+	ensure.Nil(t, err)
+
+	k, err := sookie.NewVersionedKeyring(2, map[byte][]byte{
+		1: secret,
+		2: bytes.Repeat([]byte("b"), 32),
+	})
+	ensure.Nil(t, err)
+	c, err := k.Codec(2)
+	ensure.Nil(t, err)
+	version, err := c.KeyVersion(raw)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, version, byte(1))
+
+	var actual Flash
+	ensure.Nil(t, k.Open(raw, &actual))
+	ensure.DeepEqual(t, actual, given)
+
+	raw, err = k.Seal(time.Time{}, given)
+	ensure.Nil(t, err)
+	version, err = c.KeyVersion(raw)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, version, byte(2))
+	ensure.True(t, errors.Is(old.Open(raw, &actual), sookie.ErrUnknownKeyVersion))
+}
+
+func TestVersionedKeyringUnversioned(t *testing.T) {
+	k, err := sookie.NewVersionedKeyring(1, map[byte][]byte{1: secret})
+	ensure.Nil(t, err)
+	raw, err := sookie.Seal(secret, time.Time{}, given)
+	ensure.Nil(t, err)
+	var actual Flash
+	ensure.True(t, errors.Is(k.Open(raw, &actual), sookie.ErrUnknownKeyVersion))
+
+	// a Codec with a version still opens values sealed without one
+	c, err := k.Codec(1)
+	ensure.Nil(t, err)
+	ensure.Nil(t, c.Open(raw, &actual))
+	ensure.DeepEqual(t, actual, given)
+}
+
+func TestWithKeyVersionMismatch(t *testing.T) {
+	a, err := sookie.New(secret, sookie.WithKeyVersion(1))
+	ensure.Nil(t, err)
+	b, err := sookie.New(secret, sookie.WithKeyVersion(2))
+	ensure.Nil(t, err)
+	raw, err := a.Seal(time.Time{}, given)
+	ensure.Nil(t, err)
+	var actual Flash
+	ensure.True(t, errors.Is(b.Open(raw, &actual), sookie.ErrDecrypt))
+}
+
+func TestNewVersionedKeyringMissingCurrent(t *testing.T) {
+	_, err := sookie.NewVersionedKeyring(2, map[byte][]byte{1: secret})
+	ensure.NotNil(t, err)
+}
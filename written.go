@@ -0,0 +1,64 @@
+package sookie
+
+import (
+	"net/http"
+)
+
+// writtenReporter is implemented by response writers which track whether the
+// response headers have been written, such as the one returned by
+// TrackWrites and those from many routers and middleware packages.
+type writtenReporter interface {
+	Written() bool
+}
+
+// checkWritten returns the ErrHeadersWritten error if the response headers
+// have already been written, so a new Set-Cookie header would be dropped.
+// Writers which do not report this, directly or through an Unwrap method,
+// are assumed to not have been written yet.
+func checkWritten(w http.ResponseWriter) error {
+	for w != nil {
+		if r, ok := w.(writtenReporter); ok {
+			if r.Written() {
+				return ErrHeadersWritten
+			}
+			return nil
+		}
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return nil
+		}
+		w = u.Unwrap()
+	}
+	return nil
+}
+
+// TrackWrites wraps the response writer to track whether the response headers
+// have been written, which allows Set to return the ErrHeadersWritten error
+// instead of the cookie being silently dropped. The returned writer supports
+// http.ResponseController through its Unwrap method.
+func TrackWrites(w http.ResponseWriter) http.ResponseWriter {
+	return &trackingWriter{ResponseWriter: w}
+}
+
+type trackingWriter struct {
+	http.ResponseWriter
+	written bool
+}
+
+func (t *trackingWriter) WriteHeader(code int) {
+	t.written = true
+	t.ResponseWriter.WriteHeader(code)
+}
+
+func (t *trackingWriter) Write(b []byte) (int, error) {
+	t.written = true
+	return t.ResponseWriter.Write(b)
+}
+
+func (t *trackingWriter) Flush() {
+	t.written = true
+	_ = http.NewResponseController(t.ResponseWriter).Flush()
+}
+
+func (t *trackingWriter) Written() bool               { return t.written }
+func (t *trackingWriter) Unwrap() http.ResponseWriter { return t.ResponseWriter }
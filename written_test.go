@@ -0,0 +1,43 @@
+package sookie_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/daaku/ensure"
+	"github.com/daaku/sookie"
+)
+
+func TestSetAfterHeadersWritten(t *testing.T) {
+	w := sookie.TrackWrites(httptest.NewRecorder())
+	ensure.Nil(t, sookie.Set(secret, w, given, http.Cookie{Name: cookieName}))
+	_, err := w.Write([]byte("body"))
+	ensure.Nil(t, err)
+	err = sookie.Set(secret, w, given, http.Cookie{Name: cookieName})
+	ensure.True(t, errors.Is(err, sookie.ErrHeadersWritten))
+}
+
+type wrappedWriter struct {
+	http.ResponseWriter
+}
+
+func (w wrappedWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+func TestSetAfterHeadersWrittenUnwrap(t *testing.T) {
+	tracked := sookie.TrackWrites(httptest.NewRecorder())
+	tracked.WriteHeader(http.StatusOK)
+	w := wrappedWriter{tracked}
+	err := sookie.Set(secret, w, given, http.Cookie{Name: cookieName})
+	ensure.True(t, errors.Is(err, sookie.ErrHeadersWritten))
+}
+
+func TestTrackWritesFlush(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w := sookie.TrackWrites(rec)
+	ensure.Nil(t, http.NewResponseController(w).Flush())
+	ensure.True(t, rec.Flushed)
+	err := sookie.Set(secret, w, given, http.Cookie{Name: cookieName})
+	ensure.True(t, errors.Is(err, sookie.ErrHeadersWritten))
+}
@@ -0,0 +1,24 @@
+package sookie
+
+import "reflect"
+
+// WithRejectZero configures whether opening a value fails with the
+// ErrZeroValue error when it holds the zero value of the destination type,
+// such as an empty session struct, which is usually a bug in the code that
+// sealed it. Types with an IsZero method, such as time.Time, use it, and
+// other values are compared using reflect.Value.IsZero, so a struct with an
+// empty but non-nil slice is not zero.
+func WithRejectZero(reject bool) Option {
+	return func(c *Codec) error {
+		c.rejectZero = reject
+		return nil
+	}
+}
+
+// isZero reports if v holds the zero value of its type.
+func isZero(v reflect.Value) bool {
+	if z, ok := v.Interface().(interface{ IsZero() bool }); ok {
+		return z.IsZero()
+	}
+	return v.IsZero()
+}
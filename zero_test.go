@@ -0,0 +1,41 @@
+package sookie_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/daaku/ensure"
+	"github.com/daaku/sookie"
+)
+
+func TestRejectZero(t *testing.T) {
+	c, err := sookie.New(secret, sookie.WithRejectZero(true))
+	ensure.Nil(t, err)
+	raw, err := c.Seal(time.Time{}, Flash{})
+	ensure.Nil(t, err)
+	var actual Flash
+	ensure.True(t, errors.Is(c.Open(raw, &actual), sookie.ErrZeroValue))
+
+	raw, err = c.Seal(time.Time{}, given)
+	ensure.Nil(t, err)
+	ensure.Nil(t, c.Open(raw, &actual))
+	ensure.DeepEqual(t, actual, given)
+}
+
+func TestRejectZeroIsZeroMethod(t *testing.T) {
+	c, err := sookie.New(secret, sookie.WithRejectZero(true))
+	ensure.Nil(t, err)
+	raw, err := c.Seal(time.Time{}, time.Time{})
+	ensure.Nil(t, err)
+	var actual time.Time
+	ensure.True(t, errors.Is(c.Open(raw, &actual), sookie.ErrZeroValue))
+}
+
+func TestZeroAllowedByDefault(t *testing.T) {
+	raw, err := sookie.Seal(secret, time.Time{}, Flash{})
+	ensure.Nil(t, err)
+	actual, err := sookie.Open[Flash](secret, raw)
+	ensure.Nil(t, err)
+	ensure.DeepEqual(t, actual, Flash{})
+}
@@ -0,0 +1,47 @@
+package sookie_test
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/daaku/ensure"
+	"github.com/daaku/sookie"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// zstdFrames are Zstandard frames of a bytes value never expiring, sealed at
+// 2020-01-01, holding "sookie " 20 times, pinned so upgrading the zstd
+// library can not silently break opening existing cookies.
+var zstdFrames = map[string]string{
+	// zstd -19, the reference implementation v1.5.6
+	"reference": "28b52ffd249cd5000088ff000000005e0be100736f6f6b696520730200022a7433700596f6a905",
+	// github.com/klauspost/compress/zstd v1.19.1, the default encoder
+	"klauspost": "28b52ffd0400d500000401ff000000005e0be100736f6f6b696520020002155ac99c1096f6a905",
+}
+
+// sealFrame seals a compressed bytes value by hand, following the format
+// described in the readme.
+func sealFrame(t *testing.T, frame []byte) string {
+	aead, err := chacha20poly1305.NewX(secret)
+	ensure.Nil(t, err)
+	header := []byte{1, 4, 1}
+	message := append(header, make([]byte, sookie.NonceSize)...)
+	nonce := message[len(header):]
+	message = aead.Seal(message, nonce, frame, header)
+	return base64.RawURLEncoding.EncodeToString(message)
+}
+
+func TestZstdFramesCompatible(t *testing.T) {
+	for name, frame := range zstdFrames {
+		t.Run(name, func(t *testing.T) {
+			b, err := hex.DecodeString(frame)
+			ensure.Nil(t, err)
+			payload, expires, err := sookie.OpenBytesValue(secret, sealFrame(t, b))
+			ensure.Nil(t, err)
+			ensure.True(t, expires.IsZero())
+			ensure.DeepEqual(t, string(payload), strings.Repeat("sookie ", 20))
+		})
+	}
+}